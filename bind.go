@@ -0,0 +1,146 @@
+package line
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	typeDuration = reflect.TypeFor[time.Duration]()
+	typeTime     = reflect.TypeFor[time.Time]()
+)
+
+// bindValues sets the fields of the struct pointed to by dst from values.
+//
+// A field is matched by the name in its tag, then the name in its json tag, then its name case-insensitively.
+// Fields tagged with "-" are skipped.
+func bindValues(values map[string][]string, dst any, tag string) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errors.Errorf("bind values into %T, which is not a pointer to struct", dst)
+	}
+
+	lower := make(map[string][]string, len(values))
+	for key, vals := range values {
+		lower[strings.ToLower(key)] = vals
+	}
+
+	v = v.Elem()
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := fieldName(field, tag)
+		if name == "-" {
+			continue
+		}
+
+		vals, ok := values[name]
+		if !ok {
+			vals, ok = lower[strings.ToLower(name)]
+		}
+
+		if !ok || len(vals) == 0 {
+			continue
+		}
+
+		if err := setValue(v.Field(i), vals); err != nil {
+			return errors.Errorf("bind %s, err: %+v", name, err)
+		}
+	}
+
+	return nil
+}
+
+func fieldName(field reflect.StructField, tag string) string {
+	for _, key := range []string{tag, "json"} {
+		if name, _, _ := strings.Cut(field.Tag.Get(key), ","); len(name) != 0 {
+			return name
+		}
+	}
+
+	return field.Name
+}
+
+func setValue(v reflect.Value, vals []string) error {
+	switch {
+	case v.Kind() == reflect.Pointer:
+		elem := reflect.New(v.Type().Elem())
+		if err := setValue(elem.Elem(), vals); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8:
+		slice := reflect.MakeSlice(v.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := setValue(slice.Index(i), []string{val}); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+
+	return setScalar(v, vals[len(vals)-1])
+}
+
+func setScalar(v reflect.Value, s string) error {
+	switch v.Type() {
+	case typeDuration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case typeTime:
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", time.DateOnly} {
+			if t, err := time.Parse(layout, s); err == nil {
+				v.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		return errors.Errorf("invalid time: %q", s)
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return errors.Errorf("unsupported field type: %s", v.Type())
+	}
+
+	return nil
+}
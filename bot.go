@@ -1,15 +1,78 @@
 package line
 
 import (
-	"log"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/line/line-bot-sdk-go/v8/linebot/webhook"
 	"github.com/pkg/errors"
 	"github.com/yanun0323/line/internal"
 )
 
+// BotOption is the option for the bot.
+type BotOption struct {
+	// ReadTimeout is the maximum duration for reading the entire webhook request, including the body. Zero means no timeout.
+	ReadTimeout time.Duration
+	// ReadHeaderTimeout is the amount of time allowed to read the request headers. Zero means ReadTimeout is used.
+	ReadHeaderTimeout time.Duration
+	// WriteTimeout is the maximum duration before timing out writes of the response. Zero means no timeout.
+	WriteTimeout time.Duration
+	// IdleTimeout is the maximum amount of time to wait for the next request when keep-alives are enabled. Zero means ReadTimeout is used.
+	IdleTimeout time.Duration
+	// MaxBodySize is the maximum size in bytes of the webhook request body. Zero means no limit.
+	MaxBodySize int64
+	// TLSCertFile is the certificate file used by ListenAndServe to serve HTTPS. TLSKeyFile must be set together.
+	TLSCertFile string
+	// TLSKeyFile is the private key file used by ListenAndServe to serve HTTPS. TLSCertFile must be set together.
+	TLSKeyFile string
+	// ServeMux is the mux which ListenAndServe registers the callback path on. A new mux is used if it's nil.
+	ServeMux *http.ServeMux
+	// HandlerTimeout is the deadline of the context passed to each event handler. Zero means no timeout.
+	HandlerTimeout time.Duration
+	// Async makes the bot respond to LINE right after the signature is verified, and handle the events on a worker pool.
+	// The handler errors can't affect the webhook response in this mode, but the events which can't be dispatched can, see [ErrorPolicy].
+	Async bool
+	// Workers is the number of workers handling events in async mode. Zero means 8.
+	Workers int
+	// QueueSize is the number of events which can wait for a worker in async mode. Zero means 1024.
+	QueueSize int
+	// SourceQueueSize is the number of events from the same user, group or room which can wait in async mode. Zero means no limit except QueueSize.
+	//
+	// Events from the same source are always handled one by one in order, while events from different sources are handled in parallel.
+	SourceQueueSize int
+	// QueueFullPolicy decides what to do with new events when the queue is full in async mode.
+	QueueFullPolicy QueueFullPolicy
+	// EventIDStore records the handled webhook event IDs, so that events redelivered by LINE are handled only once.
+	// An event whose handler fails is forgotten, so its redelivery is handled again.
+	// An in-memory store which forgets IDs after 24 hours is used if it's nil.
+	EventIDStore EventIDStore
+	// DisableDeduplication makes the bot handle every redelivered event again.
+	DisableDeduplication bool
+	// MentionedOnly makes the bot ignore the message events in groups and rooms which don't mention the bot,
+	// so that it doesn't respond to every message of the members.
+	MentionedOnly bool
+	// ErrorPolicy decides the status of the webhook response when a handler fails. Zero means always 200.
+	ErrorPolicy ErrorPolicy
+	// Logger is the logger of the bot. A text logger writing to stderr is used if it's nil, which is colored on terminals.
+	Logger *slog.Logger
+}
+
+// Bot is the interface for the bot. It implements [http.Handler], so it can be mounted on any mux as the webhook callback.
+//
+// Each Set*EventHandler method replaces the previous handler of the event. Use [On] to add more handlers.
+// The handlers can be set while the bot is serving, and the events being handled keep the handlers they started with.
 type Bot interface {
+	http.Handler
+
 	//	ListenAndServe listens on the TCP network address addr and then calls [Serve] to handle requests on incoming connections. Accepted connections are configured to enable TCP keep-alives.
 	//
 	//	If addr is blank, ":http" is used.
@@ -17,31 +80,171 @@ type Bot interface {
 	//	ListenAndServe always returns a non-nil error. After [Server.Shutdown] or [Server.Close], the returned error is [ErrServerClosed].
 	ListenAndServe(addr string, callbackPath string) error
 
+	//	Shutdown gracefully shuts down the bot. It stops accepting new webhooks, closes the server started by [ListenAndServe],
+	//	and waits for the running and queued event handlers to finish.
+	//
+	//	If the context is done before all handlers finish, Shutdown returns an error wrapping [ErrShutdownTimeout]
+	//	which reports the handlers that didn't finish.
+	Shutdown(ctx context.Context) error
+
+	//	Use adds middlewares which wrap the handlers of every event, in the order they are added.
+	//
+	//	Use [UseFor] to add middlewares for a specific event type.
+	Use(middlewares ...Middleware[any])
+
+	//	SetErrorHandler sets the handler for the errors returned by the event handlers.
+	SetErrorHandler(ErrorHandler)
+
+	//	SetCallbackHandler sets the handler which receives the whole webhook request before its events are handled,
+	//	e.g. to read the destination or the fields which the library doesn't model yet.
+	//
+	//	If it returns an error, the events of the request aren't handled.
+	SetCallbackHandler(func(context.Context, *webhook.CallbackRequest) error)
+
 	//	SetJoinEventHandler sets the handler for join events.
-	SetJoinEventHandler(func(EventJoin) error)
+	SetJoinEventHandler(func(context.Context, EventJoin) error)
 
 	//	SetLeaveEventHandler sets the handler for leave events.
-	SetLeaveEventHandler(func(EventLeave) error)
+	SetLeaveEventHandler(func(context.Context, EventLeave) error)
 
 	//	SetMemberJoinedEventHandler sets the handler for member joined events.
-	SetMemberJoinedEventHandler(func(EventMemberJoined) error)
+	SetMemberJoinedEventHandler(func(context.Context, EventMemberJoined) error)
 
 	//	SetMemberLeftEventHandler sets the handler for member left events.
-	SetMemberLeftEventHandler(func(EventMemberLeft) error)
+	SetMemberLeftEventHandler(func(context.Context, EventMemberLeft) error)
 
 	//	SetMessageEventHandler sets the handler for message events.
-	SetMessageEventHandler(func(EventMessage) error)
+	SetMessageEventHandler(func(context.Context, EventMessage) error)
+
+	//	SetStickerEventHandler sets the handler for sticker message events.
+	SetStickerEventHandler(func(context.Context, EventSticker) error)
+
+	//	SetImageEventHandler sets the handler for image message events.
+	SetImageEventHandler(func(context.Context, EventImage) error)
+
+	//	SetVideoEventHandler sets the handler for video message events.
+	SetVideoEventHandler(func(context.Context, EventVideo) error)
+
+	//	SetAudioEventHandler sets the handler for audio message events.
+	SetAudioEventHandler(func(context.Context, EventAudio) error)
+
+	//	SetFileEventHandler sets the handler for file message events.
+	SetFileEventHandler(func(context.Context, EventFile) error)
+
+	//	SetLocationEventHandler sets the handler for location message events.
+	SetLocationEventHandler(func(context.Context, EventLocation) error)
+
+	//	SetFollowEventHandler sets the handler for follow events.
+	SetFollowEventHandler(func(context.Context, EventFollow) error)
+
+	//	SetUnfollowEventHandler sets the handler for unfollow events.
+	SetUnfollowEventHandler(func(context.Context, EventUnfollow) error)
+
+	//	SetPostbackEventHandler sets the handler for postback events.
+	SetPostbackEventHandler(func(context.Context, EventPostback) error)
+
+	//	SetUnsendEventHandler sets the handler for unsend events.
+	SetUnsendEventHandler(func(context.Context, EventUnsend) error)
+
+	//	SetVideoPlayCompleteEventHandler sets the handler for video play complete events.
+	SetVideoPlayCompleteEventHandler(func(context.Context, EventVideoPlayComplete) error)
+
+	//	SetBeaconEventHandler sets the handler for beacon events.
+	SetBeaconEventHandler(func(context.Context, EventBeacon) error)
+
+	//	SetAccountLinkEventHandler sets the handler for account link events.
+	SetAccountLinkEventHandler(func(context.Context, EventAccountLink) error)
+
+	//	SetMembershipEventHandler sets the handler for membership events.
+	SetMembershipEventHandler(func(context.Context, EventMembership) error)
+
+	//	SetModuleEventHandler sets the handler for module events.
+	SetModuleEventHandler(func(context.Context, EventModule) error)
+
+	//	SetActivatedEventHandler sets the handler for activated events.
+	SetActivatedEventHandler(func(context.Context, EventActivated) error)
+
+	//	SetDeactivatedEventHandler sets the handler for deactivated events.
+	SetDeactivatedEventHandler(func(context.Context, EventDeactivated) error)
+
+	//	SetBotSuspendedEventHandler sets the handler for bot suspended events.
+	SetBotSuspendedEventHandler(func(context.Context, EventBotSuspended) error)
+
+	//	SetBotResumedEventHandler sets the handler for bot resumed events.
+	SetBotResumedEventHandler(func(context.Context, EventBotResumed) error)
+
+	//	SetRawEventHandler sets the fallback handler for the events which the library doesn't support yet,
+	//	including message events with unsupported message content.
+	SetRawEventHandler(func(context.Context, webhook.EventInterface) error)
+}
+
+// botCore is implemented by the bots created by [NewBot], so that [UseFor] and [On] can reach the bot
+// while [Bot] can still be implemented outside the package, e.g. by fakes in tests.
+type botCore interface {
+	core() *bot
 }
 
 type bot struct {
 	channelSecret string
+	option        BotOption
+	logger        *slog.Logger
+
+	mu         sync.Mutex
+	server     *http.Server
+	closed     atomic.Bool
+	inflight   *inflight
+	dispatcher *dispatcher
+	eventIDs   EventIDStore
 
-	joinEventHandler         func(EventJoin) error
-	leaveEventHandler        func(EventLeave) error
-	memberJoinedEventHandler func(EventMemberJoined) error
-	memberLeftEventHandler   func(EventMemberLeft) error
-	messageEventHandler      func(EventMessage) error
-	stickerEventHandler      func(EventSticker) error
+	middlewaresMu     sync.RWMutex
+	globalMiddlewares []Middleware[any]
+	middlewares       map[reflect.Type][]any
+
+	listenersMu sync.RWMutex
+	listeners   map[reflect.Type][]*listener
+
+	handlersMu sync.RWMutex
+	eventHandlers
+}
+
+// eventHandlers is the handlers set by the Set*Handler methods of [Bot].
+type eventHandlers struct {
+	errorHandler    ErrorHandler
+	callbackHandler func(context.Context, *webhook.CallbackRequest) error
+
+	joinEventHandler              func(context.Context, EventJoin) error
+	leaveEventHandler             func(context.Context, EventLeave) error
+	memberJoinedEventHandler      func(context.Context, EventMemberJoined) error
+	memberLeftEventHandler        func(context.Context, EventMemberLeft) error
+	messageEventHandler           func(context.Context, EventMessage) error
+	stickerEventHandler           func(context.Context, EventSticker) error
+	imageEventHandler             func(context.Context, EventImage) error
+	videoEventHandler             func(context.Context, EventVideo) error
+	audioEventHandler             func(context.Context, EventAudio) error
+	fileEventHandler              func(context.Context, EventFile) error
+	locationEventHandler          func(context.Context, EventLocation) error
+	followEventHandler            func(context.Context, EventFollow) error
+	unfollowEventHandler          func(context.Context, EventUnfollow) error
+	postbackEventHandler          func(context.Context, EventPostback) error
+	unsendEventHandler            func(context.Context, EventUnsend) error
+	videoPlayCompleteEventHandler func(context.Context, EventVideoPlayComplete) error
+	beaconEventHandler            func(context.Context, EventBeacon) error
+	accountLinkEventHandler       func(context.Context, EventAccountLink) error
+	membershipEventHandler        func(context.Context, EventMembership) error
+	moduleEventHandler            func(context.Context, EventModule) error
+	activatedEventHandler         func(context.Context, EventActivated) error
+	deactivatedEventHandler       func(context.Context, EventDeactivated) error
+	botSuspendedEventHandler      func(context.Context, EventBotSuspended) error
+	botResumedEventHandler        func(context.Context, EventBotResumed) error
+	rawEventHandler               func(context.Context, webhook.EventInterface) error
+}
+
+// handlers returns a snapshot of the handlers, which can be set while the events are handled.
+func (b *bot) handlers() eventHandlers {
+	b.handlersMu.RLock()
+	defer b.handlersMu.RUnlock()
+
+	return b.eventHandlers
 }
 
 // NewBot creates a new bot which is used to handle events from LINE.
@@ -61,7 +264,7 @@ type bot struct {
 //	}
 //
 //	// set the message event handler
-//	bot.SetMessageEventHandler(func(event EventMessage) error {
+//	bot.SetMessageEventHandler(func(ctx context.Context, event EventMessage) error {
 //		_, err := notifier.ReplyMessage(event.Data.ReplyToken, "Hello, world!")
 //		if err != nil {
 //			return err
@@ -74,53 +277,360 @@ type bot struct {
 //	if err := bot.ListenAndServe(":8080", "/callback"); err != nil {
 //		log.Fatal(err)
 //	}
-func NewBot(channelSecret string) (Bot, error) {
-	return &bot{
+//
+//	// or mount the bot on your own mux
+//	mux.Handle("/callback", bot)
+func NewBot(channelSecret string, opt ...BotOption) (Bot, error) {
+	option := BotOption{}
+	if len(opt) != 0 {
+		option = opt[0]
+	}
+
+	if (len(option.TLSCertFile) == 0) != (len(option.TLSKeyFile) == 0) {
+		return nil, errors.New("TLSCertFile and TLSKeyFile must be set together")
+	}
+
+	if option.MaxBodySize < 0 {
+		return nil, errors.Errorf("invalid MaxBodySize: %d", option.MaxBodySize)
+	}
+
+	if option.HandlerTimeout < 0 {
+		return nil, errors.Errorf("invalid HandlerTimeout: %s", option.HandlerTimeout)
+	}
+
+	if option.Workers < 0 || option.QueueSize < 0 || option.SourceQueueSize < 0 {
+		return nil, errors.Errorf("invalid Workers: %d, QueueSize: %d or SourceQueueSize: %d", option.Workers, option.QueueSize, option.SourceQueueSize)
+	}
+
+	logger := option.Logger
+	if logger == nil {
+		logger = internal.NewLogger(os.Stderr)
+	}
+
+	b := &bot{
 		channelSecret: channelSecret,
-	}, nil
+		option:        option,
+		logger:        logger,
+		inflight:      newInflight(),
+		middlewares:   map[reflect.Type][]any{},
+		listeners:     map[reflect.Type][]*listener{},
+	}
+
+	if !option.DisableDeduplication {
+		b.eventIDs = option.EventIDStore
+		if b.eventIDs == nil {
+			b.eventIDs = NewMemoryEventIDStore(defaultEventIDTTL)
+		}
+	}
+
+	if option.Async {
+		b.dispatcher = newDispatcher(option.Workers, option.QueueSize, option.SourceQueueSize, option.QueueFullPolicy)
+	}
+
+	return b, nil
 }
 
 func (b *bot) ListenAndServe(addr string, callbackPath string) error {
-	mux := http.NewServeMux()
-	mux.HandleFunc(callbackPath, b.HandleEvent)
+	if b.closed.Load() {
+		return http.ErrServerClosed
+	}
+
+	mux := b.option.ServeMux
+	if mux == nil {
+		mux = http.NewServeMux()
+	}
+	mux.Handle(callbackPath, b)
 
 	server := &http.Server{
-		Addr:    addr,
-		Handler: mux,
+		Addr:              addr,
+		Handler:           mux,
+		ReadTimeout:       b.option.ReadTimeout,
+		ReadHeaderTimeout: b.option.ReadHeaderTimeout,
+		WriteTimeout:      b.option.WriteTimeout,
+		IdleTimeout:       b.option.IdleTimeout,
+	}
+
+	b.mu.Lock()
+	b.server = server
+	b.mu.Unlock()
+
+	if b.closed.Load() {
+		return http.ErrServerClosed
 	}
 
-	log.Printf("\033[32mINFO\033[0m Serve Line Bot on %s", addr)
+	if len(b.option.TLSCertFile) != 0 {
+		b.logger.Info("serve line bot", "addr", addr, "tls", true)
+		return server.ListenAndServeTLS(b.option.TLSCertFile, b.option.TLSKeyFile)
+	}
+
+	b.logger.Info("serve line bot", "addr", addr, "tls", false)
 	return server.ListenAndServe()
 }
 
-func (b *bot) SetJoinEventHandler(handler func(EventJoin) error) {
+func (b *bot) Shutdown(ctx context.Context) error {
+	b.closed.Store(true)
+
+	b.mu.Lock()
+	server := b.server
+	b.mu.Unlock()
+
+	var serverErr error
+	if server != nil {
+		serverErr = server.Shutdown(ctx)
+	}
+
+	if b.dispatcher != nil {
+		b.dispatcher.close()
+	}
+
+	select {
+	case <-b.inflight.wait():
+	case <-ctx.Done():
+		return errors.Wrapf(ErrShutdownTimeout, "unfinished handlers: %v", b.inflight.unfinished())
+	}
+
+	if serverErr != nil {
+		return errors.Errorf("shutdown server, err: %+v", serverErr)
+	}
+
+	return nil
+}
+
+func (b *bot) core() *bot {
+	return b
+}
+
+func (b *bot) Use(middlewares ...Middleware[any]) {
+	b.middlewaresMu.Lock()
+	defer b.middlewaresMu.Unlock()
+
+	// copy on write, so that the running events keep their snapshot.
+	b.globalMiddlewares = append(append([]Middleware[any]{}, b.globalMiddlewares...), middlewares...)
+}
+
+func (b *bot) SetErrorHandler(handler ErrorHandler) {
+	b.handlersMu.Lock()
+	defer b.handlersMu.Unlock()
+
+	b.errorHandler = handler
+}
+
+func (b *bot) SetCallbackHandler(handler func(context.Context, *webhook.CallbackRequest) error) {
+	b.handlersMu.Lock()
+	defer b.handlersMu.Unlock()
+
+	b.callbackHandler = handler
+}
+
+func (b *bot) SetJoinEventHandler(handler func(context.Context, EventJoin) error) {
+	b.handlersMu.Lock()
+	defer b.handlersMu.Unlock()
+
 	b.joinEventHandler = handler
 }
 
-func (b *bot) SetLeaveEventHandler(handler func(EventLeave) error) {
+func (b *bot) SetLeaveEventHandler(handler func(context.Context, EventLeave) error) {
+	b.handlersMu.Lock()
+	defer b.handlersMu.Unlock()
+
 	b.leaveEventHandler = handler
 }
 
-func (b *bot) SetMemberJoinedEventHandler(handler func(EventMemberJoined) error) {
+func (b *bot) SetMemberJoinedEventHandler(handler func(context.Context, EventMemberJoined) error) {
+	b.handlersMu.Lock()
+	defer b.handlersMu.Unlock()
+
 	b.memberJoinedEventHandler = handler
 }
 
-func (b *bot) SetMemberLeftEventHandler(handler func(EventMemberLeft) error) {
+func (b *bot) SetMemberLeftEventHandler(handler func(context.Context, EventMemberLeft) error) {
+	b.handlersMu.Lock()
+	defer b.handlersMu.Unlock()
+
 	b.memberLeftEventHandler = handler
 }
 
-func (b *bot) SetMessageEventHandler(handler func(EventMessage) error) {
+func (b *bot) SetMessageEventHandler(handler func(context.Context, EventMessage) error) {
+	b.handlersMu.Lock()
+	defer b.handlersMu.Unlock()
+
 	b.messageEventHandler = handler
 }
 
+func (b *bot) SetStickerEventHandler(handler func(context.Context, EventSticker) error) {
+	b.handlersMu.Lock()
+	defer b.handlersMu.Unlock()
+
+	b.stickerEventHandler = handler
+}
+
+func (b *bot) SetImageEventHandler(handler func(context.Context, EventImage) error) {
+	b.handlersMu.Lock()
+	defer b.handlersMu.Unlock()
+
+	b.imageEventHandler = handler
+}
+
+func (b *bot) SetVideoEventHandler(handler func(context.Context, EventVideo) error) {
+	b.handlersMu.Lock()
+	defer b.handlersMu.Unlock()
+
+	b.videoEventHandler = handler
+}
+
+func (b *bot) SetAudioEventHandler(handler func(context.Context, EventAudio) error) {
+	b.handlersMu.Lock()
+	defer b.handlersMu.Unlock()
+
+	b.audioEventHandler = handler
+}
+
+func (b *bot) SetFileEventHandler(handler func(context.Context, EventFile) error) {
+	b.handlersMu.Lock()
+	defer b.handlersMu.Unlock()
+
+	b.fileEventHandler = handler
+}
+
+func (b *bot) SetLocationEventHandler(handler func(context.Context, EventLocation) error) {
+	b.handlersMu.Lock()
+	defer b.handlersMu.Unlock()
+
+	b.locationEventHandler = handler
+}
+
+func (b *bot) SetFollowEventHandler(handler func(context.Context, EventFollow) error) {
+	b.handlersMu.Lock()
+	defer b.handlersMu.Unlock()
+
+	b.followEventHandler = handler
+}
+
+func (b *bot) SetUnfollowEventHandler(handler func(context.Context, EventUnfollow) error) {
+	b.handlersMu.Lock()
+	defer b.handlersMu.Unlock()
+
+	b.unfollowEventHandler = handler
+}
+
+func (b *bot) SetPostbackEventHandler(handler func(context.Context, EventPostback) error) {
+	b.handlersMu.Lock()
+	defer b.handlersMu.Unlock()
+
+	b.postbackEventHandler = handler
+}
+
+func (b *bot) SetUnsendEventHandler(handler func(context.Context, EventUnsend) error) {
+	b.handlersMu.Lock()
+	defer b.handlersMu.Unlock()
+
+	b.unsendEventHandler = handler
+}
+
+func (b *bot) SetVideoPlayCompleteEventHandler(handler func(context.Context, EventVideoPlayComplete) error) {
+	b.handlersMu.Lock()
+	defer b.handlersMu.Unlock()
+
+	b.videoPlayCompleteEventHandler = handler
+}
+
+func (b *bot) SetBeaconEventHandler(handler func(context.Context, EventBeacon) error) {
+	b.handlersMu.Lock()
+	defer b.handlersMu.Unlock()
+
+	b.beaconEventHandler = handler
+}
+
+func (b *bot) SetAccountLinkEventHandler(handler func(context.Context, EventAccountLink) error) {
+	b.handlersMu.Lock()
+	defer b.handlersMu.Unlock()
+
+	b.accountLinkEventHandler = handler
+}
+
+func (b *bot) SetMembershipEventHandler(handler func(context.Context, EventMembership) error) {
+	b.handlersMu.Lock()
+	defer b.handlersMu.Unlock()
+
+	b.membershipEventHandler = handler
+}
+
+func (b *bot) SetModuleEventHandler(handler func(context.Context, EventModule) error) {
+	b.handlersMu.Lock()
+	defer b.handlersMu.Unlock()
+
+	b.moduleEventHandler = handler
+}
+
+func (b *bot) SetActivatedEventHandler(handler func(context.Context, EventActivated) error) {
+	b.handlersMu.Lock()
+	defer b.handlersMu.Unlock()
+
+	b.activatedEventHandler = handler
+}
+
+func (b *bot) SetDeactivatedEventHandler(handler func(context.Context, EventDeactivated) error) {
+	b.handlersMu.Lock()
+	defer b.handlersMu.Unlock()
+
+	b.deactivatedEventHandler = handler
+}
+
+func (b *bot) SetBotSuspendedEventHandler(handler func(context.Context, EventBotSuspended) error) {
+	b.handlersMu.Lock()
+	defer b.handlersMu.Unlock()
+
+	b.botSuspendedEventHandler = handler
+}
+
+func (b *bot) SetBotResumedEventHandler(handler func(context.Context, EventBotResumed) error) {
+	b.handlersMu.Lock()
+	defer b.handlersMu.Unlock()
+
+	b.botResumedEventHandler = handler
+}
+
+func (b *bot) SetRawEventHandler(handler func(context.Context, webhook.EventInterface) error) {
+	b.handlersMu.Lock()
+	defer b.handlersMu.Unlock()
+
+	b.rawEventHandler = handler
+}
+
+func (b *bot) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	b.HandleEvent(w, req)
+}
+
 func (b *bot) HandleEvent(w http.ResponseWriter, req *http.Request) {
+	if b.closed.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	// track the request before checking closed again, so that Shutdown either waits for it or it's rejected here,
+	// even if the bot is mounted on another mux and Shutdown can't wait for the server.
+	requestDone := b.inflight.add("webhook request")
+	defer requestDone()
+
+	if b.closed.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	if b.option.MaxBodySize > 0 {
+		req.Body = http.MaxBytesReader(w, req.Body, b.option.MaxBodySize)
+	}
+
 	// log.Print("/callback called...")
-	cb, err := webhook.ParseRequest(b.channelSecret, req)
+	cb, rawEvents, err := b.parseRequest(req)
 	if err != nil {
-		log.Printf("parse request, err: %+v", err)
+		b.logger.Error("parse request", "error", err)
 
+		var maxBytesErr *http.MaxBytesError
 		if errors.Is(err, webhook.ErrInvalidSignature) {
 			w.WriteHeader(http.StatusBadRequest)
+		} else if errors.As(err, &maxBytesErr) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
 		} else {
 			w.WriteHeader(http.StatusInternalServerError)
 		}
@@ -128,120 +638,620 @@ func (b *bot) HandleEvent(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if callbackHandler := b.handlers().callbackHandler; callbackHandler != nil {
+		if err := callbackHandler(req.Context(), cb); err != nil {
+			b.logger.Error("handle callback", "destination", cb.Destination, "error", err)
+			if b.option.ErrorPolicy.fail(err) {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+	}
+
 	// log.Print("Handling events...")
-	for _, event := range cb.Events {
+	failed := false
+	for i, event := range cb.Events {
 		// log.Printf("Start handling event: %T", event)
-		var (
-			err error
-		)
-		switch e := event.(type) {
-		case webhook.JoinEvent:
-			err = invoke(b.joinEventHandler, EventJoin{
+		raw := rawEvents[i]
+		id := webhookEventID(event)
+		attrs := b.eventAttrs(event)
+		if !b.claim(id) {
+			b.logger.Info("skip duplicated event", attrs...)
+			continue
+		}
+
+		done := b.inflight.add(handlerName(fmt.Sprintf("%T", event), id))
+		run := func(ctx context.Context) error {
+			defer done()
+
+			// recover the panics in the conversion of the event as well, which would crash the worker in async mode.
+			handle := recovery(func(ctx context.Context, event webhook.EventInterface) error {
+				return b.handleEvent(ctx, event, raw)
+			})
+
+			start := time.Now()
+			if err := handle(ctx, event); err != nil {
+				b.release(id)
+				attrs = append(attrs, "latency", time.Since(start), "error", err)
+				var panicErr *PanicError
+				if errors.As(err, &panicErr) {
+					attrs = append(attrs, "stack", string(panicErr.Stack))
+				}
+
+				b.logger.Error("handle event", attrs...)
+				b.handleError(ctx, event, err)
+				return err
+			}
+
+			b.logger.Debug("handle event", append(attrs, "latency", time.Since(start))...)
+			return nil
+		}
+
+		if b.dispatcher == nil {
+			if err := run(req.Context()); b.option.ErrorPolicy.fail(err) {
+				failed = true
+			}
+			continue
+		}
+
+		// the request context is canceled once the response is sent, so only its values are kept.
+		ctx := context.WithoutCancel(req.Context())
+		key := b.getSource(webhookSource(event)).key()
+		err := b.dispatcher.submit(key, func() { _ = run(ctx) })
+		if err != nil {
+			done()
+			b.release(id)
+			b.logger.Error("dispatch event", append(attrs, "error", err)...)
+
+			// the event isn't handled at all, so LINE can safely redeliver it.
+			err = Retryable(errors.Wrap(err, "dispatch event"))
+			b.handleError(ctx, event, err)
+			if b.option.ErrorPolicy.fail(err) {
+				failed = true
+			}
+		}
+	}
+
+	if failed {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// parseRequest verifies the signature of the webhook request, and parses it along with the raw JSON of each event.
+func (b *bot) parseRequest(req *http.Request) (*webhook.CallbackRequest, []json.RawMessage, error) {
+	defer func() { _ = req.Body.Close() }()
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !webhook.ValidateSignature(b.channelSecret, req.Header.Get("x-line-signature"), body) {
+		return nil, nil, webhook.ErrInvalidSignature
+	}
+
+	cb := &webhook.CallbackRequest{}
+	if err := json.Unmarshal(body, cb); err != nil {
+		return nil, nil, errors.Errorf("unmarshal request body, err: %+v", err)
+	}
+
+	var raw struct {
+		Events []json.RawMessage `json:"events"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, nil, errors.Errorf("unmarshal raw events, err: %+v", err)
+	}
+
+	if len(raw.Events) != len(cb.Events) {
+		return nil, nil, errors.Errorf("mismatched number of raw events: %d, expected: %d", len(raw.Events), len(cb.Events))
+	}
+
+	return cb, raw.Events, nil
+}
+
+// handleError passes the error of the webhook event to the error handler.
+func (b *bot) handleError(ctx context.Context, event webhook.EventInterface, err error) {
+	errorHandler := b.handlers().errorHandler
+	if errorHandler == nil {
+		return
+	}
+
+	ctx = withEvent(ctx, webhookEventID(event), b.getSource(webhookSource(event)))
+	errorHandler(ctx, event, err)
+}
+
+// eventAttrs returns the log attributes of the webhook event.
+func (b *bot) eventAttrs(event webhook.EventInterface) []any {
+	return []any{
+		"event_type", event.GetType(),
+		"webhook_event_id", webhookEventID(event),
+		"source", b.getSource(webhookSource(event)),
+	}
+}
+
+// claim reports whether the event with the webhook event ID should be handled.
+func (b *bot) claim(webhookEventID string) bool {
+	if b.eventIDs == nil || len(webhookEventID) == 0 {
+		return true
+	}
+
+	ok, err := b.eventIDs.Claim(webhookEventID)
+	if err != nil {
+		b.logger.Error("claim event", "webhook_event_id", webhookEventID, "error", err)
+		return true
+	}
+
+	return ok
+}
+
+// release lets the redelivery of the event with the webhook event ID be handled again.
+func (b *bot) release(webhookEventID string) {
+	if b.eventIDs == nil || len(webhookEventID) == 0 {
+		return
+	}
+
+	if err := b.eventIDs.Release(webhookEventID); err != nil {
+		b.logger.Error("release event", "webhook_event_id", webhookEventID, "error", err)
+	}
+}
+
+// handleEvent converts the webhook event into the typed event and invokes its handler.
+func (b *bot) handleEvent(ctx context.Context, event webhook.EventInterface, raw json.RawMessage) error {
+	id := webhookEventID(event)
+	redelivery := webhookIsRedelivery(event)
+	src := b.getSource(webhookSource(event))
+	ctx = withEvent(ctx, id, src)
+	if b.option.HandlerTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.option.HandlerTimeout)
+		defer cancel()
+	}
+
+	h := b.handlers()
+
+	var err error
+	switch e := event.(type) {
+	case webhook.JoinEvent:
+		err = invoke(ctx, b, h.joinEventHandler, EventJoin{
+			WebhookEventID: e.WebhookEventId,
+			Source:         src,
+			Timestamp:      e.Timestamp,
+			IsRedelivery:   redelivery,
+			Raw:            event,
+			RawJSON:        raw,
+			Data: EventJoinData{
+				ReplyToken: e.ReplyToken,
+			},
+		})
+	case webhook.LeaveEvent:
+		err = invoke(ctx, b, h.leaveEventHandler, EventLeave{
+			WebhookEventID: e.WebhookEventId,
+			Source:         src,
+			Timestamp:      e.Timestamp,
+			IsRedelivery:   redelivery,
+			Raw:            event,
+			RawJSON:        raw,
+			Data:           EventLeaveData{},
+		})
+	case webhook.MemberJoinedEvent:
+		err = invoke(ctx, b, h.memberJoinedEventHandler, EventMemberJoined{
+			WebhookEventID: e.WebhookEventId,
+			Source:         src,
+			Timestamp:      e.Timestamp,
+			IsRedelivery:   redelivery,
+			Raw:            event,
+			RawJSON:        raw,
+			Data: EventMemberJoinedData{
+				ReplyToken: e.ReplyToken,
+				JoinedMemberIDs: mappingPtr(e.Joined, func(members *webhook.JoinedMembers) []string {
+					ids := make([]string, len(members.Members))
+					for i, m := range members.Members {
+						ids[i] = m.UserId
+					}
+					return ids
+				}),
+			},
+		})
+	case webhook.MemberLeftEvent:
+		err = invoke(ctx, b, h.memberLeftEventHandler, EventMemberLeft{
+			WebhookEventID: e.WebhookEventId,
+			Source:         src,
+			Timestamp:      e.Timestamp,
+			IsRedelivery:   redelivery,
+			Raw:            event,
+			RawJSON:        raw,
+			Data: EventMemberLeftData{
+				LeftMemberIDs: mappingPtr(e.Left, func(members *webhook.LeftMembers) []string {
+					ids := make([]string, len(members.Members))
+					for i, m := range members.Members {
+						ids[i] = m.UserId
+					}
+					return ids
+				}),
+			},
+		})
+	case webhook.MessageEvent:
+		if b.option.MentionedOnly && (src.IsGroup() || src.IsRoom()) && !mentionsSelf(e.Message) {
+			b.logger.Debug("skip unmentioned message", b.eventAttrs(event)...)
+			return nil
+		}
+
+		switch message := e.Message.(type) {
+		case webhook.TextMessageContent:
+			err = invoke(ctx, b, h.messageEventHandler, EventMessage{
 				WebhookEventID: e.WebhookEventId,
-				Source:         b.getSource(e.Source),
+				Source:         src,
 				Timestamp:      e.Timestamp,
-				Data: eventJoinData{
-					ReplyToken: e.ReplyToken,
+				IsRedelivery:   redelivery,
+				Raw:            event,
+				RawJSON:        raw,
+				Data: EventMessageData{
+					ReplyToken:      e.ReplyToken,
+					MessageID:       message.Id,
+					Text:            message.Text,
+					QuoteToken:      message.QuoteToken,
+					QuotedMessageID: message.QuotedMessageId,
+					Mentionees:      getMentionees(message.Mention),
+					Emojis: mapping(message.Emojis, func(emojis []webhook.Emoji) []Emoji {
+						result := make([]Emoji, len(emojis))
+						for i, e := range emojis {
+							result[i] = Emoji{Index: e.Index, Length: e.Length, ProductID: e.ProductId, EmojiID: e.EmojiId}
+						}
+						return result
+					}),
 				},
 			})
-		case webhook.LeaveEvent:
-			err = invoke(b.leaveEventHandler, EventLeave{
+		case webhook.StickerMessageContent:
+			err = invoke(ctx, b, h.stickerEventHandler, EventSticker{
 				WebhookEventID: e.WebhookEventId,
-				Source:         b.getSource(e.Source),
+				Source:         src,
 				Timestamp:      e.Timestamp,
-				Data:           eventLeaveData{},
+				IsRedelivery:   redelivery,
+				Raw:            event,
+				RawJSON:        raw,
+				Data: EventStickerData{
+					ReplyToken:          e.ReplyToken,
+					MessageID:           message.Id,
+					PackageID:           message.PackageId,
+					StickerID:           message.StickerId,
+					StickerResourceType: string(message.StickerResourceType),
+					Keywords:            message.Keywords,
+					Text:                message.Text,
+					QuoteToken:          message.QuoteToken,
+					QuotedMessageID:     message.QuotedMessageId,
+				},
 			})
-		case webhook.MemberJoinedEvent:
-			err = invoke(b.memberJoinedEventHandler, EventMemberJoined{
+		case webhook.ImageMessageContent:
+			err = invoke(ctx, b, h.imageEventHandler, EventImage{
 				WebhookEventID: e.WebhookEventId,
-				Source:         b.getSource(e.Source),
+				Source:         src,
 				Timestamp:      e.Timestamp,
-				Data: eventMemberJoinedData{
-					ReplyToken: e.ReplyToken,
-					JoinedMemberIDs: mapping(e.Joined.Members, func(members []webhook.UserSource) []string {
-						ids := make([]string, len(members))
-						for i, m := range members {
-							ids[i] = m.UserId
-						}
-						return ids
+				IsRedelivery:   redelivery,
+				Raw:            event,
+				RawJSON:        raw,
+				Data: EventImageData{
+					ReplyToken:      e.ReplyToken,
+					MessageID:       message.Id,
+					QuoteToken:      message.QuoteToken,
+					ContentProvider: getContentProvider(message.ContentProvider),
+					ImageSet: mappingPtr(message.ImageSet, func(s *webhook.ImageSet) ImageSet {
+						return ImageSet{ID: s.Id, Index: s.Index, Total: s.Total}
 					}),
 				},
 			})
-		case webhook.MemberLeftEvent:
-			err = invoke(b.memberLeftEventHandler, EventMemberLeft{
+		case webhook.VideoMessageContent:
+			err = invoke(ctx, b, h.videoEventHandler, EventVideo{
 				WebhookEventID: e.WebhookEventId,
-				Source:         b.getSource(e.Source),
+				Source:         src,
 				Timestamp:      e.Timestamp,
-				Data: eventMemberLeftData{
-					LeftMemberIDs: mapping(e.Left.Members, func(members []webhook.UserSource) []string {
-						ids := make([]string, len(members))
-						for i, m := range members {
-							ids[i] = m.UserId
-						}
-						return ids
-					}),
+				IsRedelivery:   redelivery,
+				Raw:            event,
+				RawJSON:        raw,
+				Data: EventVideoData{
+					ReplyToken:      e.ReplyToken,
+					MessageID:       message.Id,
+					QuoteToken:      message.QuoteToken,
+					Duration:        message.Duration,
+					ContentProvider: getContentProvider(message.ContentProvider),
+				},
+			})
+		case webhook.AudioMessageContent:
+			err = invoke(ctx, b, h.audioEventHandler, EventAudio{
+				WebhookEventID: e.WebhookEventId,
+				Source:         src,
+				Timestamp:      e.Timestamp,
+				IsRedelivery:   redelivery,
+				Raw:            event,
+				RawJSON:        raw,
+				Data: EventAudioData{
+					ReplyToken:      e.ReplyToken,
+					MessageID:       message.Id,
+					Duration:        message.Duration,
+					ContentProvider: getContentProvider(message.ContentProvider),
+				},
+			})
+		case webhook.FileMessageContent:
+			err = invoke(ctx, b, h.fileEventHandler, EventFile{
+				WebhookEventID: e.WebhookEventId,
+				Source:         src,
+				Timestamp:      e.Timestamp,
+				IsRedelivery:   redelivery,
+				Raw:            event,
+				RawJSON:        raw,
+				Data: EventFileData{
+					ReplyToken: e.ReplyToken,
+					MessageID:  message.Id,
+					FileName:   message.FileName,
+					FileSize:   message.FileSize,
+				},
+			})
+		case webhook.LocationMessageContent:
+			err = invoke(ctx, b, h.locationEventHandler, EventLocation{
+				WebhookEventID: e.WebhookEventId,
+				Source:         src,
+				Timestamp:      e.Timestamp,
+				IsRedelivery:   redelivery,
+				Raw:            event,
+				RawJSON:        raw,
+				Data: EventLocationData{
+					ReplyToken: e.ReplyToken,
+					MessageID:  message.Id,
+					Title:      message.Title,
+					Address:    message.Address,
+					Latitude:   message.Latitude,
+					Longitude:  message.Longitude,
 				},
 			})
-		case webhook.MessageEvent:
-			switch message := e.Message.(type) {
-			case webhook.TextMessageContent:
-				err = invoke(b.messageEventHandler, EventMessage{
-					WebhookEventID: e.WebhookEventId,
-					Source:         b.getSource(e.Source),
-					Timestamp:      e.Timestamp,
-					Data: eventMessageData{
-						ReplyToken:      e.ReplyToken,
-						MessageID:       message.Id,
-						Text:            message.Text,
-						QuoteToken:      message.QuoteToken,
-						QuotedMessageID: message.QuotedMessageId,
-					},
-				})
-			case webhook.StickerMessageContent:
-				err = invoke(b.stickerEventHandler, EventSticker{
-					WebhookEventID: e.WebhookEventId,
-					Source:         b.getSource(e.Source),
-					Timestamp:      e.Timestamp,
-					Data: eventStickerData{
-						ReplyToken: e.ReplyToken,
-						PackageID:  message.PackageId,
-						StickerID:  message.StickerId,
-					},
-				})
-			default:
-				err = errors.Errorf("unsupported message content: %T", message)
-			}
 		default:
-			err = errors.Errorf("unsupported event: %T", event)
+			err = b.handleRawEvent(ctx, h.rawEventHandler, event, errors.Errorf("unsupported message content: %T", message))
 		}
+	case webhook.FollowEvent:
+		err = invoke(ctx, b, h.followEventHandler, EventFollow{
+			WebhookEventID: e.WebhookEventId,
+			Source:         src,
+			Timestamp:      e.Timestamp,
+			IsRedelivery:   redelivery,
+			Raw:            event,
+			RawJSON:        raw,
+			Data: EventFollowData{
+				ReplyToken:  e.ReplyToken,
+				IsUnblocked: e.Follow != nil && e.Follow.IsUnblocked,
+			},
+		})
+	case webhook.UnfollowEvent:
+		err = invoke(ctx, b, h.unfollowEventHandler, EventUnfollow{
+			WebhookEventID: e.WebhookEventId,
+			Source:         src,
+			Timestamp:      e.Timestamp,
+			IsRedelivery:   redelivery,
+			Raw:            event,
+			RawJSON:        raw,
+			Data:           EventUnfollowData{},
+		})
+	case webhook.PostbackEvent:
+		err = invoke(ctx, b, h.postbackEventHandler, EventPostback{
+			WebhookEventID: e.WebhookEventId,
+			Source:         src,
+			Timestamp:      e.Timestamp,
+			IsRedelivery:   redelivery,
+			Raw:            event,
+			RawJSON:        raw,
+			Data: EventPostbackData{
+				ReplyToken: e.ReplyToken,
+				Data:       mappingPtr(e.Postback, func(p *webhook.PostbackContent) string { return p.Data }),
+				Params:     mappingPtr(e.Postback, func(p *webhook.PostbackContent) map[string]string { return p.Params }),
+			},
+		})
+	case webhook.UnsendEvent:
+		err = invoke(ctx, b, h.unsendEventHandler, EventUnsend{
+			WebhookEventID: e.WebhookEventId,
+			Source:         src,
+			Timestamp:      e.Timestamp,
+			IsRedelivery:   redelivery,
+			Raw:            event,
+			RawJSON:        raw,
+			Data: EventUnsendData{
+				MessageID: mappingPtr(e.Unsend, func(u *webhook.UnsendDetail) string { return u.MessageId }),
+			},
+		})
+	case webhook.VideoPlayCompleteEvent:
+		err = invoke(ctx, b, h.videoPlayCompleteEventHandler, EventVideoPlayComplete{
+			WebhookEventID: e.WebhookEventId,
+			Source:         src,
+			Timestamp:      e.Timestamp,
+			IsRedelivery:   redelivery,
+			Raw:            event,
+			RawJSON:        raw,
+			Data: EventVideoPlayCompleteData{
+				ReplyToken: e.ReplyToken,
+				TrackingID: mappingPtr(e.VideoPlayComplete, func(v *webhook.VideoPlayComplete) string { return v.TrackingId }),
+			},
+		})
+	case webhook.BeaconEvent:
+		err = invoke(ctx, b, h.beaconEventHandler, EventBeacon{
+			WebhookEventID: e.WebhookEventId,
+			Source:         src,
+			Timestamp:      e.Timestamp,
+			IsRedelivery:   redelivery,
+			Raw:            event,
+			RawJSON:        raw,
+			Data: EventBeaconData{
+				ReplyToken: e.ReplyToken,
+				HWID:       mappingPtr(e.Beacon, func(c *webhook.BeaconContent) string { return c.Hwid }),
+				Type:       mappingPtr(e.Beacon, func(c *webhook.BeaconContent) string { return string(c.Type) }),
+				DM:         mappingPtr(e.Beacon, func(c *webhook.BeaconContent) string { return c.Dm }),
+			},
+		})
+	case webhook.AccountLinkEvent:
+		err = invoke(ctx, b, h.accountLinkEventHandler, EventAccountLink{
+			WebhookEventID: e.WebhookEventId,
+			Source:         src,
+			Timestamp:      e.Timestamp,
+			IsRedelivery:   redelivery,
+			Raw:            event,
+			RawJSON:        raw,
+			Data: EventAccountLinkData{
+				ReplyToken: e.ReplyToken,
+				Result:     mappingPtr(e.Link, func(c *webhook.LinkContent) string { return string(c.Result) }),
+				Nonce:      mappingPtr(e.Link, func(c *webhook.LinkContent) string { return c.Nonce }),
+			},
+		})
+	case webhook.MembershipEvent:
+		err = invoke(ctx, b, h.membershipEventHandler, EventMembership{
+			WebhookEventID: e.WebhookEventId,
+			Source:         src,
+			Timestamp:      e.Timestamp,
+			IsRedelivery:   redelivery,
+			Raw:            event,
+			RawJSON:        raw,
+			Data: mapping(e.Membership, func(c webhook.MembershipContentInterface) EventMembershipData {
+				data := EventMembershipData{ReplyToken: e.ReplyToken}
+				switch m := c.(type) {
+				case webhook.JoinedMembershipContent:
+					data.Type, data.MembershipID = m.Type, m.MembershipId
+				case webhook.LeftMembershipContent:
+					data.Type, data.MembershipID = m.Type, m.MembershipId
+				case webhook.RenewedMembershipContent:
+					data.Type, data.MembershipID = m.Type, m.MembershipId
+				case nil:
+				default:
+					data.Type = m.GetType()
+				}
+				return data
+			}),
+		})
+	case webhook.ModuleEvent:
+		err = invoke(ctx, b, h.moduleEventHandler, EventModule{
+			WebhookEventID: e.WebhookEventId,
+			Source:         src,
+			Timestamp:      e.Timestamp,
+			IsRedelivery:   redelivery,
+			Raw:            event,
+			RawJSON:        raw,
+			Data: mapping(e.Module, func(c webhook.ModuleContentInterface) EventModuleData {
+				data := EventModuleData{}
+				switch m := c.(type) {
+				case webhook.AttachedModuleContent:
+					data.Type, data.BotID, data.Scopes = m.Type, m.BotId, m.Scopes
+				case webhook.DetachedModuleContent:
+					data.Type, data.BotID, data.Reason = m.Type, m.BotId, string(m.Reason)
+				case nil:
+				default:
+					data.Type = m.GetType()
+				}
+				return data
+			}),
+		})
+	case webhook.ActivatedEvent:
+		err = invoke(ctx, b, h.activatedEventHandler, EventActivated{
+			WebhookEventID: e.WebhookEventId,
+			Source:         src,
+			Timestamp:      e.Timestamp,
+			IsRedelivery:   redelivery,
+			Raw:            event,
+			RawJSON:        raw,
+			Data: EventActivatedData{
+				ChatControlExpireAt: mappingPtr(e.ChatControl, func(c *webhook.ChatControl) int64 { return c.ExpireAt }),
+			},
+		})
+	case webhook.DeactivatedEvent:
+		err = invoke(ctx, b, h.deactivatedEventHandler, EventDeactivated{
+			WebhookEventID: e.WebhookEventId,
+			Source:         src,
+			Timestamp:      e.Timestamp,
+			IsRedelivery:   redelivery,
+			Raw:            event,
+			RawJSON:        raw,
+			Data:           EventDeactivatedData{},
+		})
+	case webhook.BotSuspendedEvent:
+		err = invoke(ctx, b, h.botSuspendedEventHandler, EventBotSuspended{
+			WebhookEventID: e.WebhookEventId,
+			Source:         src,
+			Timestamp:      e.Timestamp,
+			IsRedelivery:   redelivery,
+			Raw:            event,
+			RawJSON:        raw,
+			Data:           EventBotSuspendedData{},
+		})
+	case webhook.BotResumedEvent:
+		err = invoke(ctx, b, h.botResumedEventHandler, EventBotResumed{
+			WebhookEventID: e.WebhookEventId,
+			Source:         src,
+			Timestamp:      e.Timestamp,
+			IsRedelivery:   redelivery,
+			Raw:            event,
+			RawJSON:        raw,
+			Data:           EventBotResumedData{},
+		})
+	default:
+		err = b.handleRawEvent(ctx, h.rawEventHandler, event, errors.Errorf("unsupported event: %T", event))
+	}
 
-		if err != nil {
-			log.Printf("%sERROR%s handle event, err: %+v", internal.ColorRed, internal.ColorReset, err)
-		}
+	return err
+}
+
+// handleRawEvent invokes the raw event handlers for the unsupported event, or returns unsupportedErr if there is none.
+func (b *bot) handleRawEvent(ctx context.Context, fn func(context.Context, webhook.EventInterface) error, event webhook.EventInterface, unsupportedErr error) error {
+	if len(handlersOf(b, fn)) == 0 {
+		return unsupportedErr
 	}
+
+	return invoke(ctx, b, fn, event)
+}
+
+func getContentProvider(p *webhook.ContentProvider) ContentProvider {
+	return mappingPtr(p, func(p *webhook.ContentProvider) ContentProvider {
+		return ContentProvider{
+			Type:               string(p.Type),
+			OriginalContentURL: p.OriginalContentUrl,
+			PreviewImageURL:    p.PreviewImageUrl,
+		}
+	})
+}
+
+func getMentionees(m *webhook.Mention) []Mentionee {
+	return mappingPtr(m, func(m *webhook.Mention) []Mentionee {
+		mentionees := make([]Mentionee, 0, len(m.Mentionees))
+		for _, mentionee := range m.Mentionees {
+			switch mm := mentionee.(type) {
+			case webhook.UserMentionee:
+				mentionees = append(mentionees, Mentionee{Index: mm.Index, Length: mm.Length, Type: mm.GetType(), UserID: mm.UserId, IsSelf: mm.IsSelf})
+			case webhook.AllMentionee:
+				mentionees = append(mentionees, Mentionee{Index: mm.Index, Length: mm.Length, Type: mm.GetType()})
+			}
+		}
+		return mentionees
+	})
+}
+
+// mentionsSelf reports whether the message content is a text which mentions the bot.
+func mentionsSelf(content webhook.MessageContentInterface) bool {
+	text, ok := content.(webhook.TextMessageContent)
+	return ok && EventMessageData{Mentionees: getMentionees(text.Mention)}.IsMentioned()
 }
 
-func (b *bot) getSource(s webhook.SourceInterface) source {
+func (b *bot) getSource(s webhook.SourceInterface) Source {
 	switch ss := s.(type) {
 	case webhook.UserSource:
-		return source{
+		return Source{
 			Type:   SourceTypeUser,
 			UserID: ss.UserId,
 		}
 	case webhook.GroupSource:
-		return source{
+		return Source{
 			Type:    SourceTypeGroup,
 			UserID:  ss.UserId,
 			GroupID: ss.GroupId,
 		}
 	case webhook.RoomSource:
-		return source{
+		return Source{
 			Type:   SourceTypeRoom,
 			UserID: ss.UserId,
 			RoomID: ss.RoomId,
 		}
 	default:
-		return source{
+		return Source{
 			Type: SourceTypeNotFound,
 		}
 	}
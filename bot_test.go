@@ -0,0 +1,591 @@
+package line
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/webhook"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+const testChannelSecret = "test-channel-secret"
+
+func newTestRequest(t *testing.T, body string) *http.Request {
+	t.Helper()
+
+	h := hmac.New(sha256.New, []byte(testChannelSecret))
+	h.Write([]byte(body))
+
+	req := httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(body))
+	req.Header.Set("X-Line-Signature", base64.StdEncoding.EncodeToString(h.Sum(nil)))
+	return req
+}
+
+func TestBotServeHTTP(t *testing.T) {
+	b, err := NewBot(testChannelSecret, BotOption{MaxBodySize: 64})
+	require.NoError(t, err)
+
+	{
+		rec := httptest.NewRecorder()
+		b.ServeHTTP(rec, newTestRequest(t, `{"destination":"U0","events":[]}`))
+		require.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	{
+		req := newTestRequest(t, `{"destination":"U0","events":[]}`)
+		req.Header.Set("X-Line-Signature", "invalid")
+		rec := httptest.NewRecorder()
+		b.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	}
+
+	{
+		rec := httptest.NewRecorder()
+		b.ServeHTTP(rec, newTestRequest(t, `{"destination":"U0","events":[]}`+strings.Repeat(" ", 64)))
+		require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	}
+
+	{
+		require.NoError(t, b.Shutdown(context.Background()))
+		rec := httptest.NewRecorder()
+		b.ServeHTTP(rec, newTestRequest(t, `{"destination":"U0","events":[]}`))
+		require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	}
+}
+
+func TestBotShutdown(t *testing.T) {
+	b, err := NewBot(testChannelSecret)
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	b.SetMessageEventHandler(func(ctx context.Context, event EventMessage) error {
+		close(started)
+		<-release
+		return nil
+	})
+
+	served := make(chan int)
+	go func() {
+		rec := httptest.NewRecorder()
+		b.ServeHTTP(rec, newTestRequest(t, fmt.Sprintf(testMessageEvent, 0)))
+		served <- rec.Code
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = b.Shutdown(ctx)
+	require.ErrorIs(t, err, ErrShutdownTimeout)
+	require.Contains(t, err.Error(), "MessageEvent(01H00000000000000000000000)")
+
+	shutdown := make(chan error)
+	go func() { shutdown <- b.Shutdown(context.Background()) }()
+
+	select {
+	case err := <-shutdown:
+		t.Fatalf("shutdown returned before the handler finished, err: %+v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+	require.NoError(t, <-shutdown)
+	require.Equal(t, http.StatusOK, <-served)
+}
+
+const testMessageEvent = `{"destination":"U0","events":[{"type":"message","mode":"active","timestamp":1700000000000,"webhookEventId":"01H0000000000000000000000%d","deliveryContext":{"isRedelivery":false},"replyToken":"r","source":{"type":"user","userId":"U1"},"message":{"type":"text","id":"1","quoteToken":"q","text":"hello"}}]}`
+
+func TestBotHandlerContext(t *testing.T) {
+	b, err := NewBot(testChannelSecret, BotOption{HandlerTimeout: time.Minute})
+	require.NoError(t, err)
+
+	var (
+		deadline    time.Time
+		hasDeadline bool
+		id          string
+		src         Source
+	)
+	b.SetMessageEventHandler(func(ctx context.Context, event EventMessage) error {
+		deadline, hasDeadline = ctx.Deadline()
+
+		var ok bool
+		id, ok = WebhookEventIDFromContext(ctx)
+		require.True(t, ok)
+		src, ok = SourceFromContext(ctx)
+		require.True(t, ok)
+		return nil
+	})
+
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	b.ServeHTTP(rec, newTestRequest(t, fmt.Sprintf(testMessageEvent, 0)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	require.True(t, hasDeadline)
+	require.WithinDuration(t, start.Add(time.Minute), deadline, time.Second)
+	require.Equal(t, "01H00000000000000000000000", id)
+	require.Equal(t, Source{Type: SourceTypeUser, UserID: "U1"}, src)
+
+	_, ok := SourceFromContext(context.Background())
+	require.False(t, ok)
+}
+
+func TestBotLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	b, err := NewBot(testChannelSecret, BotOption{Logger: logger})
+	require.NoError(t, err)
+
+	b.SetMessageEventHandler(func(ctx context.Context, event EventMessage) error {
+		return errors.New("boom")
+	})
+
+	rec := httptest.NewRecorder()
+	b.ServeHTTP(rec, newTestRequest(t, fmt.Sprintf(testMessageEvent, 0)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	log := buf.String()
+	require.Contains(t, log, `level=ERROR msg="handle event" event_type=message webhook_event_id=01H00000000000000000000000 source.type=user source.user_id=U1 latency=`)
+	require.Contains(t, log, `error="boom`)
+	require.NotContains(t, log, "\033[")
+}
+
+func TestBotAsync(t *testing.T) {
+	b, err := NewBot(testChannelSecret, BotOption{Async: true, Workers: 1, QueueSize: 1, QueueFullPolicy: QueueFullDrop})
+	require.NoError(t, err)
+
+	started := make(chan struct{}, 3)
+	release := make(chan struct{})
+	handled := make(chan string, 3)
+	b.SetMessageEventHandler(func(ctx context.Context, event EventMessage) error {
+		started <- struct{}{}
+		<-release
+		handled <- event.WebhookEventID
+		return nil
+	})
+
+	for i := range 3 {
+		rec := httptest.NewRecorder()
+		b.ServeHTTP(rec, newTestRequest(t, fmt.Sprintf(testMessageEvent, i)))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		if i == 0 {
+			<-started
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, b.Shutdown(ctx), ErrShutdownTimeout)
+
+	close(release)
+	require.NoError(t, b.Shutdown(context.Background()))
+	require.Len(t, handled, 2, "the third event should be dropped by the full queue")
+}
+
+func TestBotAsyncErrorPolicy(t *testing.T) {
+	b, err := NewBot(testChannelSecret, BotOption{Async: true, ErrorPolicy: ErrorPolicyFailOnRetryable})
+	require.NoError(t, err)
+
+	errs := make(chan error, 2)
+	b.SetErrorHandler(func(ctx context.Context, event webhook.EventInterface, err error) {
+		errs <- err
+	})
+	b.SetMessageEventHandler(func(ctx context.Context, event EventMessage) error {
+		return Retryable(errors.New("temporary"))
+	})
+
+	rec := httptest.NewRecorder()
+	b.ServeHTTP(rec, newTestRequest(t, fmt.Sprintf(testMessageEvent, 0)))
+	require.Equal(t, http.StatusOK, rec.Code, "handler errors can't affect the response in async mode")
+	require.EqualError(t, <-errs, "retryable: temporary")
+
+	// close the dispatcher only, so that the request passes the closed check but its event can't be dispatched.
+	b.(*bot).dispatcher.close()
+
+	rec = httptest.NewRecorder()
+	b.ServeHTTP(rec, newTestRequest(t, fmt.Sprintf(testMessageEvent, 1)))
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	dispatchErr := <-errs
+	require.ErrorIs(t, dispatchErr, ErrBotClosed)
+	require.True(t, IsRetryable(dispatchErr))
+}
+
+func TestBotSetHandlerWhileServing(t *testing.T) {
+	b, err := NewBot(testChannelSecret, BotOption{Async: true, DisableDeduplication: true})
+	require.NoError(t, err)
+
+	handled := make(chan struct{}, 100)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range 100 {
+			b.SetMessageEventHandler(func(ctx context.Context, event EventMessage) error {
+				handled <- struct{}{}
+				return nil
+			})
+			b.SetErrorHandler(func(ctx context.Context, event webhook.EventInterface, err error) {})
+		}
+	}()
+
+	for range 100 {
+		rec := httptest.NewRecorder()
+		b.ServeHTTP(rec, newTestRequest(t, fmt.Sprintf(testMessageEvent, 0)))
+		require.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	wg.Wait()
+	require.NoError(t, b.Shutdown(context.Background()))
+}
+
+func TestBotAsyncPanic(t *testing.T) {
+	b, err := NewBot(testChannelSecret, BotOption{Async: true})
+	require.NoError(t, err)
+
+	errs := make(chan error, 1)
+	b.SetErrorHandler(func(ctx context.Context, event webhook.EventInterface, err error) {
+		errs <- err
+	})
+
+	handled := make(chan string, 1)
+	b.SetMessageEventHandler(func(ctx context.Context, event EventMessage) error {
+		handled <- event.WebhookEventID
+		return nil
+	})
+
+	// a listener of the wrong type makes the conversion panic outside the handlers.
+	core := b.(*bot)
+	core.listeners[reflect.TypeFor[EventMessage]()] = []*listener{{handler: "not a handler"}}
+
+	rec := httptest.NewRecorder()
+	b.ServeHTTP(rec, newTestRequest(t, fmt.Sprintf(testMessageEvent, 0)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.ErrorIs(t, <-errs, ErrPanic)
+
+	delete(core.listeners, reflect.TypeFor[EventMessage]())
+
+	rec = httptest.NewRecorder()
+	b.ServeHTTP(rec, newTestRequest(t, fmt.Sprintf(testMessageEvent, 0)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "01H00000000000000000000000", <-handled, "the panicked event should be released")
+
+	require.NoError(t, b.Shutdown(context.Background()))
+}
+
+func TestBotDeduplication(t *testing.T) {
+	b, err := NewBot(testChannelSecret)
+	require.NoError(t, err)
+
+	count := 0
+	b.SetMessageEventHandler(func(ctx context.Context, event EventMessage) error {
+		count++
+		if count == 1 {
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	for range 3 {
+		rec := httptest.NewRecorder()
+		b.ServeHTTP(rec, newTestRequest(t, fmt.Sprintf(testMessageEvent, 0)))
+		require.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	require.Equal(t, 2, count, "a failed event should be handled again, a succeeded one should not")
+}
+
+func TestBotErrorPolicy(t *testing.T) {
+	b, err := NewBot(testChannelSecret, BotOption{ErrorPolicy: ErrorPolicyFailOnRetryable})
+	require.NoError(t, err)
+
+	var handlerErr error
+	b.SetErrorHandler(func(ctx context.Context, event webhook.EventInterface, err error) {
+		id, ok := WebhookEventIDFromContext(ctx)
+		require.True(t, ok)
+		require.NotEmpty(t, id)
+		handlerErr = err
+	})
+
+	b.SetMessageEventHandler(func(ctx context.Context, event EventMessage) error {
+		return errors.New("permanent")
+	})
+
+	{
+		rec := httptest.NewRecorder()
+		b.ServeHTTP(rec, newTestRequest(t, fmt.Sprintf(testMessageEvent, 0)))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.EqualError(t, handlerErr, "permanent")
+	}
+
+	b.SetMessageEventHandler(func(ctx context.Context, event EventMessage) error {
+		return Retryable(errors.New("temporary"))
+	})
+
+	{
+		rec := httptest.NewRecorder()
+		b.ServeHTTP(rec, newTestRequest(t, fmt.Sprintf(testMessageEvent, 1)))
+		require.Equal(t, http.StatusInternalServerError, rec.Code)
+		require.True(t, IsRetryable(handlerErr))
+	}
+}
+
+func TestBotEvents(t *testing.T) {
+	b, err := NewBot(testChannelSecret)
+	require.NoError(t, err)
+
+	var (
+		follow      EventFollow
+		postback    EventPostback
+		beacon      EventBeacon
+		accountLink EventAccountLink
+		location    EventLocation
+		sticker     EventSticker
+		joined      EventMemberJoined
+		left        EventMemberLeft
+		raw         []string
+	)
+	b.SetFollowEventHandler(func(ctx context.Context, event EventFollow) error {
+		follow = event
+		return nil
+	})
+	b.SetPostbackEventHandler(func(ctx context.Context, event EventPostback) error {
+		postback = event
+		return nil
+	})
+	b.SetBeaconEventHandler(func(ctx context.Context, event EventBeacon) error {
+		beacon = event
+		return nil
+	})
+	b.SetAccountLinkEventHandler(func(ctx context.Context, event EventAccountLink) error {
+		accountLink = event
+		return nil
+	})
+	b.SetLocationEventHandler(func(ctx context.Context, event EventLocation) error {
+		location = event
+		return nil
+	})
+	b.SetStickerEventHandler(func(ctx context.Context, event EventSticker) error {
+		sticker = event
+		return nil
+	})
+	b.SetMemberJoinedEventHandler(func(ctx context.Context, event EventMemberJoined) error {
+		joined = event
+		return nil
+	})
+	b.SetMemberLeftEventHandler(func(ctx context.Context, event EventMemberLeft) error {
+		left = event
+		return nil
+	})
+	b.SetRawEventHandler(func(ctx context.Context, event webhook.EventInterface) error {
+		raw = append(raw, event.GetType())
+		return nil
+	})
+
+	body := `{"destination":"U0","events":[
+		{"type":"follow","mode":"active","timestamp":1,"webhookEventId":"E1","deliveryContext":{"isRedelivery":true},"replyToken":"r1","source":{"type":"user","userId":"U1"},"follow":{"isUnblocked":true}},
+		{"type":"postback","mode":"active","timestamp":2,"webhookEventId":"E2","deliveryContext":{"isRedelivery":false},"replyToken":"r2","source":{"type":"group","groupId":"G1","userId":"U1"},"postback":{"data":"action=buy","params":{"date":"2024-01-01"}}},
+		{"type":"somethingNew","mode":"active","timestamp":3,"webhookEventId":"E3","deliveryContext":{"isRedelivery":false},"source":{"type":"user","userId":"U1"}},
+		{"type":"beacon","mode":"active","timestamp":4,"webhookEventId":"E4","deliveryContext":{"isRedelivery":false},"replyToken":"r4","source":{"type":"user","userId":"U1"}},
+		{"type":"accountLink","mode":"active","timestamp":5,"webhookEventId":"E5","deliveryContext":{"isRedelivery":false},"replyToken":"r5","source":{"type":"user","userId":"U1"}},
+		{"type":"message","mode":"active","timestamp":6,"webhookEventId":"E6","deliveryContext":{"isRedelivery":false},"replyToken":"r6","source":{"type":"user","userId":"U1"},"message":{"type":"location","id":"6","title":"Store","address":"Taipei","latitude":25.03,"longitude":121.56}},
+		{"type":"message","mode":"active","timestamp":7,"webhookEventId":"E7","deliveryContext":{"isRedelivery":false},"replyToken":"r7","source":{"type":"user","userId":"U1"},"message":{"type":"sticker","id":"7","quoteToken":"q7","quotedMessageId":"6","packageId":"789","stickerId":"10855","stickerResourceType":"MESSAGE","keywords":["hello","hi"],"text":"Hello!"}},
+		{"type":"memberJoined","mode":"active","timestamp":8,"webhookEventId":"E8","deliveryContext":{"isRedelivery":false},"replyToken":"r8","source":{"type":"group","groupId":"G1"}},
+		{"type":"memberLeft","mode":"active","timestamp":9,"webhookEventId":"E9","deliveryContext":{"isRedelivery":false},"source":{"type":"group","groupId":"G1"}}
+	]}`
+
+	rec := httptest.NewRecorder()
+	b.ServeHTTP(rec, newTestRequest(t, body))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	require.True(t, follow.IsRedelivery)
+	require.True(t, follow.Data.IsUnblocked)
+	require.Equal(t, "r1", follow.Data.ReplyToken)
+
+	require.Equal(t, SourceTypeGroup, postback.Source.Type)
+	require.Equal(t, "action=buy", postback.Data.Data)
+	require.Equal(t, "2024-01-01", postback.Data.Params["date"])
+
+	require.Equal(t, "r4", beacon.Data.ReplyToken, "the reply token should be kept without the beacon content")
+	require.Empty(t, beacon.Data.HWID)
+	require.Equal(t, "r5", accountLink.Data.ReplyToken, "the reply token should be kept without the link content")
+	require.Empty(t, accountLink.Data.Result)
+
+	require.Equal(t, EventLocationData{
+		ReplyToken: "r6",
+		MessageID:  "6",
+		Title:      "Store",
+		Address:    "Taipei",
+		Latitude:   25.03,
+		Longitude:  121.56,
+	}, location.Data)
+
+	require.Equal(t, EventStickerData{
+		ReplyToken:          "r7",
+		MessageID:           "7",
+		PackageID:           "789",
+		StickerID:           "10855",
+		StickerResourceType: "MESSAGE",
+		Keywords:            []string{"hello", "hi"},
+		Text:                "Hello!",
+		QuoteToken:          "q7",
+		QuotedMessageID:     "6",
+	}, sticker.Data)
+
+	require.Equal(t, "E8", joined.WebhookEventID)
+	require.Equal(t, "r8", joined.Data.ReplyToken, "the reply token should be kept without the joined members")
+	require.Empty(t, joined.Data.JoinedMemberIDs)
+	require.Equal(t, "E9", left.WebhookEventID)
+	require.Empty(t, left.Data.LeftMemberIDs)
+
+	require.Equal(t, []string{"somethingNew"}, raw)
+}
+
+func TestBotMediaEvents(t *testing.T) {
+	b, err := NewBot(testChannelSecret)
+	require.NoError(t, err)
+
+	var (
+		image EventImage
+		video EventVideo
+		audio EventAudio
+		file  EventFile
+	)
+	b.SetImageEventHandler(func(ctx context.Context, event EventImage) error {
+		image = event
+		return nil
+	})
+	b.SetVideoEventHandler(func(ctx context.Context, event EventVideo) error {
+		video = event
+		return nil
+	})
+	b.SetAudioEventHandler(func(ctx context.Context, event EventAudio) error {
+		audio = event
+		return nil
+	})
+	b.SetFileEventHandler(func(ctx context.Context, event EventFile) error {
+		file = event
+		return nil
+	})
+
+	body := `{"destination":"U0","events":[
+		{"type":"message","mode":"active","timestamp":1,"webhookEventId":"E1","deliveryContext":{"isRedelivery":false},"replyToken":"r1","source":{"type":"user","userId":"U1"},"message":{"type":"image","id":"1","quoteToken":"q1","contentProvider":{"type":"line"},"imageSet":{"id":"S1","index":2,"total":3}}},
+		{"type":"message","mode":"active","timestamp":2,"webhookEventId":"E2","deliveryContext":{"isRedelivery":false},"replyToken":"r2","source":{"type":"user","userId":"U1"},"message":{"type":"video","id":"2","quoteToken":"q2","duration":60000,"contentProvider":{"type":"external","originalContentUrl":"https://a/o.mp4","previewImageUrl":"https://a/p.jpg"}}},
+		{"type":"message","mode":"active","timestamp":3,"webhookEventId":"E3","deliveryContext":{"isRedelivery":false},"replyToken":"r3","source":{"type":"user","userId":"U1"},"message":{"type":"audio","id":"3","duration":1500,"contentProvider":{"type":"line"}}},
+		{"type":"message","mode":"active","timestamp":4,"webhookEventId":"E4","deliveryContext":{"isRedelivery":false},"replyToken":"r4","source":{"type":"user","userId":"U1"},"message":{"type":"file","id":"4","fileName":"report.pdf","fileSize":2048}}
+	]}`
+
+	rec := httptest.NewRecorder()
+	b.ServeHTTP(rec, newTestRequest(t, body))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	require.Equal(t, EventImageData{
+		ReplyToken:      "r1",
+		MessageID:       "1",
+		QuoteToken:      "q1",
+		ContentProvider: ContentProvider{Type: "line"},
+		ImageSet:        ImageSet{ID: "S1", Index: 2, Total: 3},
+	}, image.Data)
+
+	require.Equal(t, EventVideoData{
+		ReplyToken:      "r2",
+		MessageID:       "2",
+		QuoteToken:      "q2",
+		Duration:        60000,
+		ContentProvider: ContentProvider{Type: "external", OriginalContentURL: "https://a/o.mp4", PreviewImageURL: "https://a/p.jpg"},
+	}, video.Data)
+
+	require.Equal(t, EventAudioData{
+		ReplyToken:      "r3",
+		MessageID:       "3",
+		Duration:        1500,
+		ContentProvider: ContentProvider{Type: "line"},
+	}, audio.Data)
+
+	require.Equal(t, EventFileData{ReplyToken: "r4", MessageID: "4", FileName: "report.pdf", FileSize: 2048}, file.Data)
+}
+
+func TestBotRaw(t *testing.T) {
+	b, err := NewBot(testChannelSecret, BotOption{DisableDeduplication: true, ErrorPolicy: ErrorPolicyFailOnError})
+	require.NoError(t, err)
+
+	var (
+		destination string
+		message     EventMessage
+	)
+	b.SetCallbackHandler(func(ctx context.Context, cb *webhook.CallbackRequest) error {
+		destination = cb.Destination
+		return nil
+	})
+	b.SetMessageEventHandler(func(ctx context.Context, event EventMessage) error {
+		message = event
+		return nil
+	})
+
+	rec := httptest.NewRecorder()
+	b.ServeHTTP(rec, newTestRequest(t, fmt.Sprintf(testMessageEvent, 0)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	require.Equal(t, "U0", destination)
+	require.Equal(t, webhook.EventMode_ACTIVE, message.Raw.(webhook.MessageEvent).Mode)
+	require.Contains(t, string(message.RawJSON), `"quoteToken":"q"`)
+	require.True(t, strings.HasPrefix(string(message.RawJSON), `{"type":"message"`))
+
+	b.SetCallbackHandler(func(ctx context.Context, cb *webhook.CallbackRequest) error {
+		return errors.New("reject")
+	})
+	message = EventMessage{}
+
+	rec = httptest.NewRecorder()
+	b.ServeHTTP(rec, newTestRequest(t, fmt.Sprintf(testMessageEvent, 1)))
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.Empty(t, message.WebhookEventID)
+}
+
+func TestBotMentionedOnly(t *testing.T) {
+	b, err := NewBot(testChannelSecret, BotOption{MentionedOnly: true})
+	require.NoError(t, err)
+
+	var messages []EventMessage
+	b.SetMessageEventHandler(func(ctx context.Context, event EventMessage) error {
+		messages = append(messages, event)
+		return nil
+	})
+
+	body := `{"destination":"U0","events":[
+		{"type":"message","mode":"active","timestamp":1,"webhookEventId":"E1","deliveryContext":{"isRedelivery":false},"replyToken":"r1","source":{"type":"group","groupId":"G1","userId":"U1"},"message":{"type":"text","id":"1","quoteToken":"q","text":"hello"}},
+		{"type":"message","mode":"active","timestamp":2,"webhookEventId":"E2","deliveryContext":{"isRedelivery":false},"replyToken":"r2","source":{"type":"group","groupId":"G1","userId":"U1"},"message":{"type":"text","id":"2","quoteToken":"q","text":"@all 😀 @bot  weather","mention":{"mentionees":[{"index":0,"length":4,"type":"all"},{"index":8,"length":4,"type":"user","userId":"U0","isSelf":true}]}}},
+		{"type":"message","mode":"active","timestamp":3,"webhookEventId":"E3","deliveryContext":{"isRedelivery":false},"replyToken":"r3","source":{"type":"user","userId":"U1"},"message":{"type":"text","id":"3","quoteToken":"q","text":"hi (love)","emojis":[{"index":3,"length":6,"productId":"5ac1bfd5040ab15980c9b435","emojiId":"001"}]}}
+	]}`
+
+	rec := httptest.NewRecorder()
+	b.ServeHTTP(rec, newTestRequest(t, body))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	require.Len(t, messages, 2)
+	require.Equal(t, "E2", messages[0].WebhookEventID)
+	require.True(t, messages[0].Data.IsMentioned())
+	require.Equal(t, []Mentionee{
+		{Index: 0, Length: 4, Type: "all"},
+		{Index: 8, Length: 4, Type: "user", UserID: "U0", IsSelf: true},
+	}, messages[0].Data.Mentionees)
+	require.Equal(t, "😀 weather", messages[0].Data.TextWithoutMentions())
+
+	require.Equal(t, "E3", messages[1].WebhookEventID)
+	require.False(t, messages[1].Data.IsMentioned())
+	require.Equal(t, []Emoji{{Index: 3, Length: 6, ProductID: "5ac1bfd5040ab15980c9b435", EmojiID: "001"}}, messages[1].Data.Emojis)
+}
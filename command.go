@@ -0,0 +1,455 @@
+package line
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrUnknownCommand is returned when the message is a command which has no route.
+	ErrUnknownCommand = errors.New("line: unknown command")
+
+	// ErrInvalidCommand is returned when the arguments or flags of the command can't be parsed or bound.
+	ErrInvalidCommand = errors.New("line: invalid command")
+)
+
+const (
+	defaultCommandPrefix = "/"
+	commandArgRest       = "rest"
+)
+
+// CommandSpec describes a command.
+type CommandSpec struct {
+	// Name is the name of the command. Subcommands are named with spaces, e.g. "todo add".
+	Name string
+	// Aliases are the other names of the command, e.g. "todo new" or "ta".
+	Aliases []string
+	// Usage is the usage of the arguments and flags shown in the help text, e.g. "<title> [--due 2006-01-02]".
+	Usage string
+	// Description is the description shown in the help text.
+	Description string
+}
+
+// Command is the parsed command which is passed to the route of a [CommandRouter].
+type Command[T any] struct {
+	// Name is the name of the matched command, even if it's called by an alias.
+	Name string
+	// Args is the positional arguments.
+	Args []string
+	// Flags is the flags, e.g. {"due": ["2024-01-01"]} for "--due 2024-01-01".
+	Flags map[string][]string
+	// Input is the arguments and flags bound into T.
+	Input T
+}
+
+// CommandRouterOption is the option for the command router.
+type CommandRouterOption struct {
+	// Prefix is the prefix of the commands. Empty means "/".
+	Prefix string
+	// Notifier is used to reply the help text and the suggestions for unknown commands.
+	// Without it, the help is not replied and unknown commands return an error wrapping [ErrUnknownCommand].
+	Notifier Notifier
+	// DisableHelp disables the built-in help command, which lists the commands or describes one of them.
+	DisableHelp bool
+}
+
+type commandRoute struct {
+	spec    CommandSpec
+	handler func(ctx context.Context, event EventMessage, name string, tokens []string) error
+}
+
+// CommandRouter routes text message events like "/todo add "buy milk" --due 2024-01-01" to the commands.
+//
+// Arguments are split by spaces, and can be quoted by straight or curly, single or double quotes.
+// Flags are "--name value", "--name=value" or "--name" for bool flags, and "--" ends the flags.
+//
+// # Example:
+//
+//	type addInput struct {
+//		Title string    `command:"0"`
+//		Due   time.Time `command:"due"`
+//	}
+//
+//	router := line.NewCommandRouter(line.CommandRouterOption{Notifier: notifier})
+//	line.HandleCommand(router, line.CommandSpec{Name: "todo add", Aliases: []string{"ta"}}, func(ctx context.Context, event line.EventMessage, cmd line.Command[addInput]) error {
+//		return add(ctx, cmd.Input.Title, cmd.Input.Due)
+//	})
+//
+//	bot.SetMessageEventHandler(router.HandleEvent)
+type CommandRouter struct {
+	option   CommandRouterOption
+	routes   map[string]*commandRoute
+	ordered  []*commandRoute
+	fallback func(ctx context.Context, event EventMessage) error
+}
+
+// NewCommandRouter creates a new command router.
+func NewCommandRouter(opt ...CommandRouterOption) *CommandRouter {
+	option := CommandRouterOption{}
+	if len(opt) != 0 {
+		option = opt[0]
+	}
+
+	if len(option.Prefix) == 0 {
+		option.Prefix = defaultCommandPrefix
+	}
+
+	return &CommandRouter{
+		option: option,
+		routes: map[string]*commandRoute{},
+	}
+}
+
+// HandleCommand sets the handler for the command, which receives the arguments and flags bound into T.
+//
+// The fields of T are matched by the `command` tag: a number for the positional argument at that index,
+// "rest" for the positional arguments after the numbered ones, or the name of a flag.
+// Fields without the tag are matched with the flags by the `json` tag, then the field name case-insensitively.
+func HandleCommand[T any](r *CommandRouter, spec CommandSpec, fn func(ctx context.Context, event EventMessage, cmd Command[T]) error) {
+	boolFlags, argCount := commandFields(reflect.TypeFor[T]())
+	r.add(spec, func(ctx context.Context, event EventMessage, name string, tokens []string) error {
+		args, flags := parseCommandTokens(tokens, boolFlags)
+
+		values := make(map[string][]string, len(flags)+len(args)+1)
+		for key, vals := range flags {
+			values[key] = vals
+		}
+
+		for i, arg := range args {
+			values[strconv.Itoa(i)] = []string{arg}
+		}
+
+		if len(args) > argCount {
+			values[commandArgRest] = args[argCount:]
+		}
+
+		cmd := Command[T]{Name: name, Args: args, Flags: flags}
+		if err := bindValues(values, &cmd.Input, "command"); err != nil {
+			return errors.Wrapf(ErrInvalidCommand, "%s%s, err: %+v", r.option.Prefix, name, err)
+		}
+
+		return fn(ctx, event, cmd)
+	})
+}
+
+// Handle sets the handler for the command, which doesn't need the arguments and flags bound.
+func (r *CommandRouter) Handle(spec CommandSpec, fn func(ctx context.Context, event EventMessage, cmd Command[struct{}]) error) {
+	HandleCommand(r, spec, fn)
+}
+
+// Fallback sets the handler for the text messages which aren't commands.
+func (r *CommandRouter) Fallback(fn func(ctx context.Context, event EventMessage) error) {
+	r.fallback = fn
+}
+
+func (r *CommandRouter) add(spec CommandSpec, handler func(context.Context, EventMessage, string, []string) error) {
+	spec.Name = normalizeCommandName(spec.Name)
+	route := &commandRoute{spec: spec, handler: handler}
+	r.ordered = append(r.ordered, route)
+
+	r.routes[spec.Name] = route
+	for _, alias := range spec.Aliases {
+		r.routes[normalizeCommandName(alias)] = route
+	}
+}
+
+// HandleEvent routes the text message event. It can be set as the message event handler of [Bot].
+func (r *CommandRouter) HandleEvent(ctx context.Context, event EventMessage) error {
+	text := strings.TrimSpace(event.Data.Text)
+	if !strings.HasPrefix(text, r.option.Prefix) {
+		if r.fallback != nil {
+			return r.fallback(ctx, event)
+		}
+		return nil
+	}
+
+	tokens, err := tokenizeCommand(strings.TrimPrefix(text, r.option.Prefix))
+	if err != nil {
+		return errors.Wrapf(ErrInvalidCommand, "tokenize %q, err: %+v", text, err)
+	}
+
+	if len(tokens) == 0 {
+		if r.option.DisableHelp {
+			return nil
+		}
+		return r.reply(ctx, event, r.help(""))
+	}
+
+	// match the longest command name, so that subcommands win over their parents.
+	for n := len(tokens); n > 0; n-- {
+		name := normalizeCommandName(strings.Join(tokens[:n], " "))
+		if route, ok := r.routes[name]; ok {
+			return route.handler(ctx, event, route.spec.Name, tokens[n:])
+		}
+	}
+
+	if !r.option.DisableHelp && strings.EqualFold(tokens[0], "help") {
+		return r.reply(ctx, event, r.help(strings.Join(tokens[1:], " ")))
+	}
+
+	text = r.unknown(tokens)
+	if r.option.Notifier == nil {
+		return errors.Wrap(ErrUnknownCommand, text)
+	}
+
+	return r.reply(ctx, event, text)
+}
+
+// reply replies the plain text by the notifier if there is one.
+func (r *CommandRouter) reply(ctx context.Context, event EventMessage, text string) error {
+	if r.option.Notifier == nil || len(text) == 0 {
+		return nil
+	}
+
+	// escape the text, which has the user input, usage and descriptions, so that its braces aren't taken as substitution keys.
+	escaped, opt, err := NewTextBuilder().Text(text).Build()
+	if err != nil {
+		return errors.Errorf("build reply, err: %+v", err)
+	}
+
+	if _, err := r.option.Notifier.ReplyMessageContext(ctx, event.Data.ReplyToken, escaped, opt); err != nil {
+		return errors.Errorf("reply command, err: %+v", err)
+	}
+
+	return nil
+}
+
+// help returns the help text of the command, or of all commands if name is empty.
+func (r *CommandRouter) help(name string) string {
+	if len(name) != 0 {
+		if route, ok := r.routes[normalizeCommandName(name)]; ok {
+			return r.describe(route, true)
+		}
+	}
+
+	lines := make([]string, 0, len(r.ordered))
+	for _, route := range r.ordered {
+		lines = append(lines, r.describe(route, false))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (r *CommandRouter) describe(route *commandRoute, detail bool) string {
+	var sb strings.Builder
+	sb.WriteString(r.option.Prefix + route.spec.Name)
+	if len(route.spec.Usage) != 0 {
+		sb.WriteString(" " + route.spec.Usage)
+	}
+
+	if len(route.spec.Description) != 0 {
+		sb.WriteString(" - " + route.spec.Description)
+	}
+
+	if detail && len(route.spec.Aliases) != 0 {
+		aliases := make([]string, len(route.spec.Aliases))
+		for i, alias := range route.spec.Aliases {
+			aliases[i] = r.option.Prefix + normalizeCommandName(alias)
+		}
+		sb.WriteString("\nAliases: " + strings.Join(aliases, ", "))
+	}
+
+	return sb.String()
+}
+
+// unknown returns the reply for the unknown command, with the suggestions of similar commands.
+func (r *CommandRouter) unknown(tokens []string) string {
+	input := normalizeCommandName(tokens[0])
+
+	type suggestion struct {
+		name     string
+		distance int
+	}
+
+	var suggestions []suggestion
+	for name := range r.routes {
+		first, _, _ := strings.Cut(name, " ")
+		d := levenshtein(input, first)
+		if d <= max(2, len(input)/3) || strings.HasPrefix(first, input) {
+			suggestions = append(suggestions, suggestion{name: name, distance: d})
+		}
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].distance != suggestions[j].distance {
+			return suggestions[i].distance < suggestions[j].distance
+		}
+		return suggestions[i].name < suggestions[j].name
+	})
+
+	text := fmt.Sprintf("Unknown command %s%s.", r.option.Prefix, tokens[0])
+	if len(suggestions) != 0 {
+		names := make([]string, 0, 3)
+		for _, s := range suggestions[:min(3, len(suggestions))] {
+			names = append(names, r.option.Prefix+s.name)
+		}
+		text += " Did you mean " + strings.Join(names, ", ") + "?"
+	}
+
+	if !r.option.DisableHelp {
+		text += fmt.Sprintf(" Send %shelp to list the commands.", r.option.Prefix)
+	}
+
+	return text
+}
+
+func normalizeCommandName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// commandFields returns the names of the bool flags of T, and the number of its positional arguments.
+func commandFields(t reflect.Type) (map[string]bool, int) {
+	boolFlags := map[string]bool{}
+	argCount := 0
+	if t.Kind() != reflect.Struct {
+		return boolFlags, argCount
+	}
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name := fieldName(field, "command")
+		if n, err := strconv.Atoi(name); err == nil {
+			argCount = max(argCount, n+1)
+			continue
+		}
+
+		ft := field.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+
+		if ft.Kind() == reflect.Bool {
+			boolFlags[strings.ToLower(name)] = true
+		}
+	}
+
+	return boolFlags, argCount
+}
+
+// parseCommandTokens splits the tokens into positional arguments and flags.
+func parseCommandTokens(tokens []string, boolFlags map[string]bool) ([]string, map[string][]string) {
+	args := []string{}
+	flags := map[string][]string{}
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if token == "--" {
+			args = append(args, tokens[i+1:]...)
+			break
+		}
+
+		if !isCommandFlag(token) {
+			args = append(args, token)
+			continue
+		}
+
+		name := strings.TrimLeft(token, "-")
+		if key, value, ok := strings.Cut(name, "="); ok {
+			flags[key] = append(flags[key], value)
+			continue
+		}
+
+		if !boolFlags[strings.ToLower(name)] && i+1 < len(tokens) && !isCommandFlag(tokens[i+1]) {
+			flags[name] = append(flags[name], tokens[i+1])
+			i++
+			continue
+		}
+
+		flags[name] = append(flags[name], "true")
+	}
+
+	return args, flags
+}
+
+// isCommandFlag reports whether the token is a flag rather than an argument such as "-" or "-1".
+func isCommandFlag(token string) bool {
+	name := strings.TrimLeft(token, "-")
+	if len(name) == 0 || len(name) == len(token) {
+		return false
+	}
+
+	_, err := strconv.ParseFloat(name, 64)
+	return err != nil
+}
+
+// tokenizeCommand splits the text by spaces, keeping the quoted parts together.
+func tokenizeCommand(text string) ([]string, error) {
+	closing := map[rune]rune{'"': '"', '\'': '\'', '“': '”', '‘': '’'}
+
+	var (
+		tokens  []string
+		current strings.Builder
+		inToken bool
+		quote   rune
+		escaped bool
+	)
+
+	for _, c := range text {
+		switch {
+		case escaped:
+			current.WriteRune(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+			inToken = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				current.WriteRune(c)
+			}
+		case closing[c] != 0:
+			quote = closing[c]
+			inToken = true
+		case unicode.IsSpace(c):
+			if inToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inToken = false
+			}
+		default:
+			current.WriteRune(c)
+			inToken = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, errors.Errorf("unclosed quote %q", quote)
+	}
+
+	if inToken {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens, nil
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr := make([]int, len(rb)+1)
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev = curr
+	}
+
+	return prev[len(rb)]
+}
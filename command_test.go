@@ -0,0 +1,107 @@
+package line
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// replyRecorder is a notifier which records the replied texts.
+type replyRecorder struct {
+	Notifier
+	replies []string
+}
+
+func (r *replyRecorder) ReplyMessageContext(ctx context.Context, replyToken, text string, opt ...NotifyMessageOption) (LineMessageID, error) {
+	r.replies = append(r.replies, text)
+	return "", nil
+}
+
+func newTextEvent(text string) EventMessage {
+	return EventMessage{Data: EventMessageData{ReplyToken: "token", Text: text}}
+}
+
+func TestCommandRouter(t *testing.T) {
+	type addInput struct {
+		Title string        `command:"0"`
+		Notes []string      `command:"rest"`
+		Due   time.Time     `command:"due"`
+		Every time.Duration `command:"every"`
+		Done  bool
+		Count int
+	}
+
+	notifier := &replyRecorder{}
+	router := NewCommandRouter(CommandRouterOption{Notifier: notifier})
+
+	var got Command[addInput]
+	HandleCommand(router, CommandSpec{Name: "todo add", Aliases: []string{"ta"}, Usage: "<title>", Description: "add a todo"}, func(ctx context.Context, event EventMessage, cmd Command[addInput]) error {
+		got = cmd
+		return nil
+	})
+
+	listed := false
+	router.Handle(CommandSpec{Name: "todo", Description: "list todos"}, func(ctx context.Context, event EventMessage, cmd Command[struct{}]) error {
+		listed = true
+		return nil
+	})
+
+	require.NoError(t, router.HandleEvent(context.Background(), newTextEvent(`/todo add "buy milk" --due 2024-01-02 --done -count=3 --every 1h note1 “note 2”`)))
+	require.Equal(t, "todo add", got.Name)
+	require.Equal(t, addInput{
+		Title: "buy milk",
+		Notes: []string{"note1", "note 2"},
+		Due:   time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		Every: time.Hour,
+		Done:  true,
+		Count: 3,
+	}, got.Input)
+
+	require.NoError(t, router.HandleEvent(context.Background(), newTextEvent(`/TA 'it\'s' -- --done`)))
+	require.Equal(t, "todo add", got.Name)
+	require.Equal(t, "it's", got.Input.Title)
+	require.Equal(t, []string{"--done"}, got.Input.Notes)
+	require.False(t, got.Input.Done)
+
+	require.NoError(t, router.HandleEvent(context.Background(), newTextEvent("/todo")))
+	require.True(t, listed)
+
+	require.ErrorIs(t, router.HandleEvent(context.Background(), newTextEvent("/ta x --count many")), ErrInvalidCommand)
+	require.ErrorIs(t, router.HandleEvent(context.Background(), newTextEvent(`/ta "unclosed`)), ErrInvalidCommand)
+
+	require.NoError(t, router.HandleEvent(context.Background(), newTextEvent("/help")))
+	require.Equal(t, "/todo add <title> - add a todo\n/todo - list todos", notifier.replies[len(notifier.replies)-1])
+
+	require.NoError(t, router.HandleEvent(context.Background(), newTextEvent("/help ta")))
+	require.Equal(t, "/todo add <title> - add a todo\nAliases: /ta", notifier.replies[len(notifier.replies)-1])
+
+	require.NoError(t, router.HandleEvent(context.Background(), newTextEvent("/tood")))
+	require.Equal(t, "Unknown command /tood. Did you mean /todo, /todo add? Send /help to list the commands.", notifier.replies[len(notifier.replies)-1])
+
+	require.NoError(t, router.HandleEvent(context.Background(), newTextEvent("/{x}")))
+	require.Equal(t, "Unknown command /{{x}}. Send /help to list the commands.", notifier.replies[len(notifier.replies)-1])
+
+	router.Handle(CommandSpec{Name: "echo", Usage: "{text}"}, func(ctx context.Context, event EventMessage, cmd Command[struct{}]) error {
+		return nil
+	})
+	require.NoError(t, router.HandleEvent(context.Background(), newTextEvent("/help echo")))
+	require.Equal(t, "/echo {{text}}", notifier.replies[len(notifier.replies)-1])
+
+	fallback := false
+	router.Fallback(func(ctx context.Context, event EventMessage) error {
+		fallback = true
+		return nil
+	})
+	require.NoError(t, router.HandleEvent(context.Background(), newTextEvent("hello")))
+	require.True(t, fallback)
+
+	require.ErrorIs(t, NewCommandRouter(CommandRouterOption{Prefix: "!"}).HandleEvent(context.Background(), newTextEvent("!nope")), ErrUnknownCommand)
+}
+
+func TestTokenizeCommand(t *testing.T) {
+	tokens, err := tokenizeCommand(`a  "b c" 'd "e"' ‘f g’ h\ i ""`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b c", `d "e"`, "f g", "h i", ""}, tokens)
+}
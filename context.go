@@ -0,0 +1,29 @@
+package line
+
+import "context"
+
+type contextKey int
+
+const (
+	contextKeyWebhookEventID contextKey = iota
+	contextKeySource
+)
+
+// withEvent returns a copy of ctx which carries the webhook event ID and the source of the event.
+func withEvent(ctx context.Context, webhookEventID string, src Source) context.Context {
+	ctx = context.WithValue(ctx, contextKeyWebhookEventID, webhookEventID)
+	ctx = context.WithValue(ctx, contextKeySource, src)
+	return ctx
+}
+
+// WebhookEventIDFromContext returns the webhook event ID of the event which is being handled.
+func WebhookEventIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(contextKeyWebhookEventID).(string)
+	return id, ok
+}
+
+// SourceFromContext returns the source of the event which is being handled.
+func SourceFromContext(ctx context.Context) (Source, bool) {
+	src, ok := ctx.Value(contextKeySource).(Source)
+	return src, ok
+}
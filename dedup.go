@@ -0,0 +1,232 @@
+package line
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultEventIDTTL = 24 * time.Hour
+
+	// the file log of the IDs is compacted when it has this many times the lines of the live IDs, and at least compactMinLines lines.
+	compactRatio    = 4
+	compactMinLines = 1024
+)
+
+// EventIDStore records the webhook event IDs which have been handled, so that redelivered events are handled only once.
+type EventIDStore interface {
+	// Claim records the webhook event ID, and reports whether it is recorded for the first time.
+	Claim(webhookEventID string) (bool, error)
+
+	// Release forgets the webhook event ID, so that a redelivery of the event can be handled again.
+	Release(webhookEventID string) error
+}
+
+type memoryEventIDStore struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	expiry    map[string]time.Time
+	lastSweep time.Time
+}
+
+// NewMemoryEventIDStore creates an in-memory [EventIDStore] which forgets the IDs after ttl. Zero ttl means 24 hours.
+func NewMemoryEventIDStore(ttl time.Duration) EventIDStore {
+	if ttl <= 0 {
+		ttl = defaultEventIDTTL
+	}
+
+	return &memoryEventIDStore{
+		ttl:       ttl,
+		expiry:    map[string]time.Time{},
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *memoryEventIDStore) Claim(webhookEventID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	if exp, ok := s.expiry[webhookEventID]; ok && now.Before(exp) {
+		return false, nil
+	}
+
+	s.expiry[webhookEventID] = now.Add(s.ttl)
+	return true, nil
+}
+
+func (s *memoryEventIDStore) Release(webhookEventID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.expiry, webhookEventID)
+	return nil
+}
+
+// sweep removes the expired IDs at most once a minute. The caller must hold s.mu.
+func (s *memoryEventIDStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+
+	for id, exp := range s.expiry {
+		if !now.Before(exp) {
+			delete(s.expiry, id)
+		}
+	}
+	s.lastSweep = now
+}
+
+type fileEventIDStore struct {
+	*memoryEventIDStore
+
+	path  string
+	lines int /* number of lines in the file */
+}
+
+// NewFileEventIDStore creates an [EventIDStore] which keeps the IDs in the file at path, so that they survive restarts.
+// The IDs are forgotten after ttl. Zero ttl means 24 hours.
+//
+// The file is an append-only log, which is compacted when the store is created,
+// and when the log grows much larger than the IDs which are still kept.
+func NewFileEventIDStore(path string, ttl time.Duration) (EventIDStore, error) {
+	s := &fileEventIDStore{
+		memoryEventIDStore: NewMemoryEventIDStore(ttl).(*memoryEventIDStore),
+		path:               path,
+	}
+
+	if err := s.load(); err != nil {
+		return nil, errors.Errorf("load event id store, err: %+v", err)
+	}
+
+	if err := s.compact(); err != nil {
+		return nil, errors.Errorf("compact event id store, err: %+v", err)
+	}
+
+	return s, nil
+}
+
+func (s *fileEventIDStore) Claim(webhookEventID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if err := s.sweep(now); err != nil {
+		return false, errors.Errorf("compact event id store, err: %+v", err)
+	}
+
+	if exp, ok := s.expiry[webhookEventID]; ok && now.Before(exp) {
+		return false, nil
+	}
+
+	exp := now.Add(s.ttl)
+	if err := s.append(fmt.Sprintf("+ %s %d\n", webhookEventID, exp.UnixMilli())); err != nil {
+		return false, errors.Errorf("append event id, err: %+v", err)
+	}
+
+	s.expiry[webhookEventID] = exp
+	return true, nil
+}
+
+func (s *fileEventIDStore) Release(webhookEventID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.sweep(time.Now()); err != nil {
+		return errors.Errorf("compact event id store, err: %+v", err)
+	}
+
+	if err := s.append(fmt.Sprintf("- %s\n", webhookEventID)); err != nil {
+		return errors.Errorf("append event id, err: %+v", err)
+	}
+
+	delete(s.expiry, webhookEventID)
+	return nil
+}
+
+func (s *fileEventIDStore) append(line string) error {
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(line); err != nil {
+		return err
+	}
+
+	s.lines++
+	return nil
+}
+
+// sweep removes the expired IDs, and compacts the file once it has grown too large for the kept IDs. The caller must hold s.mu.
+func (s *fileEventIDStore) sweep(now time.Time) error {
+	s.memoryEventIDStore.sweep(now)
+
+	if s.lines < compactMinLines || s.lines < compactRatio*len(s.expiry) {
+		return nil
+	}
+
+	return s.compact()
+}
+
+func (s *fileEventIDStore) load() error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	now := time.Now()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		switch {
+		case len(fields) == 3 && fields[0] == "+":
+			ms, err := strconv.ParseInt(fields[2], 10, 64)
+			if err != nil {
+				continue
+			}
+
+			if exp := time.UnixMilli(ms); now.Before(exp) {
+				s.expiry[fields[1]] = exp
+			}
+		case len(fields) == 2 && fields[0] == "-":
+			delete(s.expiry, fields[1])
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (s *fileEventIDStore) compact() error {
+	var sb strings.Builder
+	for id, exp := range s.expiry {
+		sb.WriteString(fmt.Sprintf("+ %s %d\n", id, exp.UnixMilli()))
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(sb.String()), 0o644); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+
+	s.lines = len(s.expiry)
+	return nil
+}
@@ -0,0 +1,90 @@
+package line
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryEventIDStore(t *testing.T) {
+	s := NewMemoryEventIDStore(time.Hour)
+
+	ok, err := s.Claim("id")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = s.Claim("id")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, s.Release("id"))
+
+	ok, err = s.Claim("id")
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestFileEventIDStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "event_ids")
+
+	s, err := NewFileEventIDStore(path, time.Hour)
+	require.NoError(t, err)
+
+	for _, id := range []string{"a", "b"} {
+		ok, err := s.Claim(id)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+	require.NoError(t, s.Release("b"))
+
+	s, err = NewFileEventIDStore(path, time.Hour)
+	require.NoError(t, err)
+
+	ok, err := s.Claim("a")
+	require.NoError(t, err)
+	require.False(t, ok, "claimed id should survive restarts")
+
+	ok, err = s.Claim("b")
+	require.NoError(t, err)
+	require.True(t, ok, "released id should be claimable again")
+}
+
+func TestFileEventIDStoreCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "event_ids")
+
+	s, err := NewFileEventIDStore(path, time.Hour)
+	require.NoError(t, err)
+	store := s.(*fileEventIDStore)
+
+	for i := range compactMinLines/2 + 1 {
+		id := fmt.Sprintf("id%d", i)
+		_, err := s.Claim(id)
+		require.NoError(t, err)
+		require.NoError(t, s.Release(id))
+	}
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Less(t, strings.Count(string(b), "\n"), compactMinLines, "the log should be compacted while running")
+	require.Equal(t, store.lines, strings.Count(string(b), "\n"))
+
+	store.mu.Lock()
+	store.expiry["expired"] = time.Now().Add(-time.Minute)
+	store.lastSweep = time.Now().Add(-time.Hour)
+	store.lines = compactMinLines
+	store.mu.Unlock()
+
+	ok, err := s.Claim("live")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	b, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(b), "expired")
+	require.Contains(t, string(b), "+ live ")
+}
@@ -0,0 +1,177 @@
+package line
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrQueueFull is returned when an event is dropped because the event queue is full.
+var ErrQueueFull = errors.New("line: event queue full")
+
+const (
+	defaultWorkers   = 8
+	defaultQueueSize = 1024
+)
+
+// QueueFullPolicy decides what the bot does with a new event when the event queue is full.
+type QueueFullPolicy int
+
+const (
+	// QueueFullBlock blocks the webhook request until the queue has room, which applies backpressure to LINE.
+	QueueFullBlock QueueFullPolicy = iota
+	// QueueFullDrop drops the event and reports [ErrQueueFull].
+	QueueFullDrop
+)
+
+// keyQueue is the serial queue of the tasks which share the same key.
+type keyQueue struct {
+	key     string
+	tasks   []func()
+	running bool
+}
+
+// dispatcher runs tasks on a bounded pool of workers.
+//
+// Tasks with the same key run one by one in the order they are submitted,
+// while tasks with different keys run in parallel.
+type dispatcher struct {
+	policy    QueueFullPolicy
+	queueSize int
+	keySize   int
+
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	closed   bool
+	queues   map[string]*keyQueue
+	ready    []*keyQueue
+	pending  int
+}
+
+func newDispatcher(workers, queueSize, keySize int, policy QueueFullPolicy) *dispatcher {
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+
+	d := &dispatcher{
+		policy:    policy,
+		queueSize: queueSize,
+		keySize:   keySize,
+		queues:    map[string]*keyQueue{},
+	}
+	d.notEmpty = sync.NewCond(&d.mu)
+	d.notFull = sync.NewCond(&d.mu)
+
+	for range workers {
+		go d.work()
+	}
+
+	return d
+}
+
+func (d *dispatcher) work() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for {
+		for len(d.ready) == 0 && !d.closed {
+			d.notEmpty.Wait()
+		}
+
+		if len(d.ready) == 0 {
+			return
+		}
+
+		q := d.ready[0]
+		d.ready = d.ready[1:]
+
+		task := q.tasks[0]
+		q.tasks = q.tasks[1:]
+		q.running = true
+		d.pending--
+		d.notFull.Broadcast()
+
+		d.mu.Unlock()
+		task()
+		d.mu.Lock()
+
+		q.running = false
+		if len(q.tasks) != 0 {
+			d.ready = append(d.ready, q)
+			d.notEmpty.Signal()
+		} else if d.queues[q.key] == q {
+			delete(d.queues, q.key)
+		}
+	}
+}
+
+// submit queues the task behind the other tasks with the same key. An empty key means the task has no order to keep.
+//
+// It returns [ErrQueueFull] if the task is dropped, or [ErrBotClosed] if the dispatcher is closed.
+func (d *dispatcher) submit(key string, task func()) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for {
+		if d.closed {
+			return ErrBotClosed
+		}
+
+		if !d.full(key) {
+			break
+		}
+
+		if d.policy == QueueFullDrop {
+			return ErrQueueFull
+		}
+
+		d.notFull.Wait()
+	}
+
+	q, ok := d.queues[key]
+	if !ok {
+		q = &keyQueue{key: key}
+		if len(key) != 0 {
+			d.queues[key] = q
+		}
+	}
+
+	q.tasks = append(q.tasks, task)
+	d.pending++
+
+	if !q.running && len(q.tasks) == 1 {
+		d.ready = append(d.ready, q)
+		d.notEmpty.Signal()
+	}
+
+	return nil
+}
+
+// full reports whether a task with the key can't be queued now. The caller must hold d.mu.
+func (d *dispatcher) full(key string) bool {
+	if d.pending >= d.queueSize {
+		return true
+	}
+
+	if d.keySize <= 0 || len(key) == 0 {
+		return false
+	}
+
+	q, ok := d.queues[key]
+	return ok && len(q.tasks) >= d.keySize
+}
+
+// close stops accepting tasks. The workers exit after the queued tasks are done.
+func (d *dispatcher) close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.closed = true
+	d.notEmpty.Broadcast()
+	d.notFull.Broadcast()
+}
@@ -0,0 +1,59 @@
+package line
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatcherKeepsOrderPerKey(t *testing.T) {
+	d := newDispatcher(4, 1000, 0, QueueFullBlock)
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		got = map[string][]int{}
+	)
+
+	for i := range 100 {
+		key := fmt.Sprintf("user:%d", i%5)
+		wg.Add(1)
+		require.NoError(t, d.submit(key, func() {
+			defer wg.Done()
+			mu.Lock()
+			got[key] = append(got[key], i)
+			mu.Unlock()
+		}))
+	}
+
+	wg.Wait()
+	d.close()
+
+	for key, seq := range got {
+		require.Len(t, seq, 20, key)
+		require.IsIncreasing(t, seq, key)
+	}
+
+	require.ErrorIs(t, d.submit("user:0", func() {}), ErrBotClosed)
+}
+
+func TestDispatcherSourceQueueSize(t *testing.T) {
+	d := newDispatcher(2, 10, 1, QueueFullDrop)
+	defer d.close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	require.NoError(t, d.submit("user:1", func() {
+		close(started)
+		<-release
+	}))
+	<-started
+
+	require.NoError(t, d.submit("user:1", func() {}))
+	require.ErrorIs(t, d.submit("user:1", func() {}), ErrQueueFull)
+	require.NoError(t, d.submit("user:2", func() {}))
+
+	close(release)
+}
@@ -0,0 +1,70 @@
+package line
+
+import (
+	"context"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/webhook"
+	"github.com/pkg/errors"
+)
+
+// ErrorHandler handles the error returned by the handler of the webhook event.
+type ErrorHandler func(ctx context.Context, event webhook.EventInterface, err error)
+
+// ErrorPolicy decides the status of the webhook response when the handler of an event fails.
+//
+// LINE redelivers the webhook when the response status isn't 200, if redelivery is enabled in the LINE Developers Console.
+// It also applies to the error returned by the callback handler set by [Bot.SetCallbackHandler].
+//
+// In async mode, the webhook is responded before the events are handled, so the handler errors don't affect it.
+// It still applies to the events which can't be dispatched to the workers, e.g. when the queue is full with [QueueFullDrop]
+// or the bot is shut down. Such errors are made by [Retryable], since the events aren't handled at all.
+type ErrorPolicy int
+
+const (
+	// ErrorPolicyAlwaysOK responds 200 even if handlers fail, so LINE never redelivers.
+	ErrorPolicyAlwaysOK ErrorPolicy = iota
+	// ErrorPolicyFailOnError responds 500 if any handler fails, so LINE redelivers.
+	ErrorPolicyFailOnError
+	// ErrorPolicyFailOnRetryable responds 500 only if any handler fails with an error made by [Retryable].
+	ErrorPolicyFailOnRetryable
+)
+
+// RetryableError is an error which LINE should redeliver the event for, see [ErrorPolicyFailOnRetryable].
+type RetryableError struct {
+	Err error
+}
+
+// Retryable marks the error as retryable. It returns nil if err is nil.
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &RetryableError{Err: err}
+}
+
+// IsRetryable reports whether any error in err's chain is made by [Retryable].
+func IsRetryable(err error) bool {
+	var re *RetryableError
+	return errors.As(err, &re)
+}
+
+func (e *RetryableError) Error() string {
+	return "retryable: " + e.Err.Error()
+}
+
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+// fail reports whether the webhook should be responded with a failure status for the error.
+func (p ErrorPolicy) fail(err error) bool {
+	switch p {
+	case ErrorPolicyFailOnError:
+		return err != nil
+	case ErrorPolicyFailOnRetryable:
+		return IsRetryable(err)
+	default:
+		return false
+	}
+}
@@ -1,5 +1,18 @@
 package line
 
+import (
+	"encoding/json"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/webhook"
+	"github.com/pkg/errors"
+)
+
 // SourceType is the type of the source of the event.
 type SourceType int
 
@@ -14,64 +27,566 @@ const (
 	SourceTypeRoom
 )
 
-type source struct {
-	Type    SourceType
-	UserID  string
-	GroupID string
-	RoomID  string
+// String returns the name of the source type.
+func (t SourceType) String() string {
+	switch t {
+	case SourceTypeUser:
+		return "user"
+	case SourceTypeGroup:
+		return "group"
+	case SourceTypeRoom:
+		return "room"
+	default:
+		return "not_found"
+	}
+}
+
+// MarshalText implements [encoding.TextMarshaler], so the source type is encoded as its name in JSON.
+func (t SourceType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler].
+func (t *SourceType) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "user":
+		*t = SourceTypeUser
+	case "group":
+		*t = SourceTypeGroup
+	case "room":
+		*t = SourceTypeRoom
+	case "not_found", "":
+		*t = SourceTypeNotFound
+	default:
+		return errors.Errorf("unknown source type: %q", text)
+	}
+
+	return nil
+}
+
+// Source is the source of the event.
+type Source struct {
+	Type    SourceType `json:"type"`
+	UserID  string     `json:"userId,omitempty"`  /* may be empty in groups and rooms if the user hasn't consented */
+	GroupID string     `json:"groupId,omitempty"` /* only for group */
+	RoomID  string     `json:"roomId,omitempty"`  /* only for room */
+}
+
+// IsUser reports whether the event is from a one-on-one chat with a user.
+func (s Source) IsUser() bool {
+	return s.Type == SourceTypeUser
+}
+
+// IsGroup reports whether the event is from a group chat.
+func (s Source) IsGroup() bool {
+	return s.Type == SourceTypeGroup
+}
+
+// IsRoom reports whether the event is from a multi-person chat.
+func (s Source) IsRoom() bool {
+	return s.Type == SourceTypeRoom
+}
+
+// TargetID returns the ID to push messages to the chat of the source with [Notifier.SendMessage],
+// which is the group ID, the room ID or the user ID.
+func (s Source) TargetID() string {
+	switch s.Type {
+	case SourceTypeGroup:
+		return s.GroupID
+	case SourceTypeRoom:
+		return s.RoomID
+	default:
+		return s.UserID
+	}
+}
+
+// LogValue implements [slog.LogValuer].
+func (s Source) LogValue() slog.Value {
+	attrs := []slog.Attr{slog.String("type", s.Type.String())}
+	if len(s.UserID) != 0 {
+		attrs = append(attrs, slog.String("user_id", s.UserID))
+	}
+
+	if len(s.GroupID) != 0 {
+		attrs = append(attrs, slog.String("group_id", s.GroupID))
+	}
+
+	if len(s.RoomID) != 0 {
+		attrs = append(attrs, slog.String("room_id", s.RoomID))
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+// key returns the conversation key of the source, which is used to keep the order of its events.
+func (s Source) key() string {
+	switch s.Type {
+	case SourceTypeUser:
+		return "user:" + s.UserID
+	case SourceTypeGroup:
+		return "group:" + s.GroupID
+	case SourceTypeRoom:
+		return "room:" + s.RoomID
+	default:
+		return ""
+	}
 }
 
+// event is the common structure of the events. The events can be encoded into and decoded from JSON,
+// e.g. to be queued or to be constructed in tests.
 type event[Data any] struct {
-	WebhookEventID string
-	Source         source
-	Timestamp      int64 /* milliseconds */
-	Data           Data
+	WebhookEventID string `json:"webhookEventId"`
+	Source         Source `json:"source"`
+	Timestamp      int64  `json:"timestamp"`    /* milliseconds */
+	IsRedelivery   bool   `json:"isRedelivery"` /* the event is redelivered by LINE because the previous delivery failed */
+	Data           Data   `json:"data"`
+
+	Raw     webhook.EventInterface `json:"-"` /* the original webhook event, e.g. to read the mode or the delivery context */
+	RawJSON json.RawMessage        `json:"-"` /* the original JSON of the webhook event, e.g. to read the fields which the library doesn't model yet */
+}
+
+// millis converts the milliseconds since the Unix epoch into time, or zero time if it's zero.
+func millis(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+
+	return time.UnixMilli(ms)
+}
+
+// EventJoinData is the data of [EventJoin].
+type EventJoinData struct {
+	ReplyToken string `json:"replyToken"`
+}
+
+// EventLeaveData is the data of [EventLeave].
+type EventLeaveData struct {
+}
+
+// EventMemberJoinedData is the data of [EventMemberJoined].
+type EventMemberJoinedData struct {
+	ReplyToken      string   `json:"replyToken"`
+	JoinedMemberIDs []string `json:"joinedMemberIds"`
+}
+
+// EventMemberLeftData is the data of [EventMemberLeft].
+type EventMemberLeftData struct {
+	LeftMemberIDs []string `json:"leftMemberIds"`
+}
+
+// EventMessageData is the data of [EventMessage].
+type EventMessageData struct {
+	ReplyToken      string      `json:"replyToken"`
+	MessageID       string      `json:"messageId"`
+	Text            string      `json:"text"`
+	QuoteToken      string      `json:"quoteToken"`
+	QuotedMessageID string      `json:"quotedMessageId"`
+	Mentionees      []Mentionee `json:"mentionees"`
+	Emojis          []Emoji     `json:"emojis"`
+}
+
+// Emoji is a LINE emoji in a text message. See https://developers.line.biz/en/docs/messaging-api/emoji-list/ for the available emojis.
+//
+// Index and Length are the position of the emoji in the text of [EventMessage],
+// and are ignored when the emoji is sent with [NotifyMessageOption.Emoji].
+type Emoji struct {
+	Index     int32  `json:"index"`  /* position of the emoji in UTF-16 code units */
+	Length    int32  `json:"length"` /* length of the emoji in UTF-16 code units, e.g. 6 for "(love)" */
+	ProductID string `json:"productId"`
+	EmojiID   string `json:"emojiId"`
+}
+
+// Mentionee is a mention in the text of [EventMessage].
+type Mentionee struct {
+	Index  int32  `json:"index"`  /* position of the mention in UTF-16 code units */
+	Length int32  `json:"length"` /* length of the mention in UTF-16 code units */
+	Type   string `json:"type"`   /* user, or all for @All */
+	UserID string `json:"userId"` /* only for user; empty if the user hasn't consented to share the profile */
+	IsSelf bool   `json:"isSelf"` /* the mentioned user is the bot */
+}
+
+// IsMentioned reports whether the bot is mentioned in the message.
+// Mentions of all members by @All aren't counted.
+func (d EventMessageData) IsMentioned() bool {
+	for _, m := range d.Mentionees {
+		if m.IsSelf {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TextWithoutMentions returns the text with the mentions removed, e.g. "weather" for "@bot weather".
+//
+// The whitespace around each mention is reduced to the whitespace before it, or after it if there is none before,
+// and the result is trimmed. The other whitespace in the text, such as newlines, is kept.
+func (d EventMessageData) TextWithoutMentions() string {
+	text := utf16.Encode([]rune(d.Text))
+	mentions := append([]Mentionee{}, d.Mentionees...)
+	sort.Slice(mentions, func(i, j int) bool {
+		return mentions[i].Index < mentions[j].Index
+	})
+
+	isSpace := func(c uint16) bool { return unicode.IsSpace(rune(c)) }
+
+	stripped := make([]uint16, 0, len(text))
+	next := 0
+	for _, m := range mentions {
+		start, end := int(m.Index), int(m.Index+m.Length)
+		if start < next || end > len(text) {
+			continue
+		}
+
+		stripped = append(stripped, text[next:start]...)
+		next = end
+
+		// drop the whitespace after the mention if it's already separated from the text before.
+		if len(stripped) == 0 || isSpace(stripped[len(stripped)-1]) {
+			for next < len(text) && isSpace(text[next]) {
+				next++
+			}
+		}
+	}
+	stripped = append(stripped, text[next:]...)
+
+	return strings.TrimSpace(string(utf16.Decode(stripped)))
+}
+
+// EventStickerData is the data of [EventSticker].
+type EventStickerData struct {
+	ReplyToken          string   `json:"replyToken"`
+	MessageID           string   `json:"messageId"`
+	PackageID           string   `json:"packageId"`
+	StickerID           string   `json:"stickerId"`
+	StickerResourceType string   `json:"stickerResourceType"` /* STATIC, ANIMATION, SOUND, ANIMATION_SOUND, POPUP, POPUP_SOUND, CUSTOM, MESSAGE, NAME_TEXT or PER_STICKER_TEXT */
+	Keywords            []string `json:"keywords"`            /* max 15 keywords describing the sticker */
+	Text                string   `json:"text"`                /* the text entered by user for message stickers */
+	QuoteToken          string   `json:"quoteToken"`
+	QuotedMessageID     string   `json:"quotedMessageId"`
+}
+
+// ContentProvider is the provider of the content of an image, video or audio message.
+type ContentProvider struct {
+	Type               string `json:"type"`               /* line or external */
+	OriginalContentURL string `json:"originalContentUrl"` /* only for external */
+	PreviewImageURL    string `json:"previewImageUrl"`    /* only for external */
+}
+
+// ImageSet is the set of the images sent at once, which the image message belongs to.
+type ImageSet struct {
+	ID    string `json:"id"`    /* empty if the image isn't sent as a set */
+	Index int32  `json:"index"` /* 1-based index in the set */
+	Total int32  `json:"total"`
+}
+
+// EventImageData is the data of [EventImage].
+type EventImageData struct {
+	ReplyToken      string          `json:"replyToken"`
+	MessageID       string          `json:"messageId"`
+	QuoteToken      string          `json:"quoteToken"`
+	ContentProvider ContentProvider `json:"contentProvider"`
+	ImageSet        ImageSet        `json:"imageSet"`
+}
+
+// EventVideoData is the data of [EventVideo].
+type EventVideoData struct {
+	ReplyToken      string          `json:"replyToken"`
+	MessageID       string          `json:"messageId"`
+	QuoteToken      string          `json:"quoteToken"`
+	Duration        int64           `json:"duration"` /* milliseconds */
+	ContentProvider ContentProvider `json:"contentProvider"`
+}
+
+// EventAudioData is the data of [EventAudio].
+type EventAudioData struct {
+	ReplyToken      string          `json:"replyToken"`
+	MessageID       string          `json:"messageId"`
+	Duration        int64           `json:"duration"` /* milliseconds */
+	ContentProvider ContentProvider `json:"contentProvider"`
+}
+
+// EventFileData is the data of [EventFile].
+type EventFileData struct {
+	ReplyToken string `json:"replyToken"`
+	MessageID  string `json:"messageId"`
+	FileName   string `json:"fileName"`
+	FileSize   int32  `json:"fileSize"` /* bytes */
+}
+
+// EventLocationData is the data of [EventLocation].
+type EventLocationData struct {
+	ReplyToken string  `json:"replyToken"`
+	MessageID  string  `json:"messageId"`
+	Title      string  `json:"title"`
+	Address    string  `json:"address"`
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+}
+
+// EventFollowData is the data of [EventFollow].
+type EventFollowData struct {
+	ReplyToken  string `json:"replyToken"`
+	IsUnblocked bool   `json:"isUnblocked"` /* the user unblocked the bot rather than added it as a friend for the first time */
+}
+
+// EventUnfollowData is the data of [EventUnfollow].
+type EventUnfollowData struct {
+}
+
+// EventPostbackData is the data of [EventPostback].
+type EventPostbackData struct {
+	ReplyToken string            `json:"replyToken"`
+	Data       string            `json:"data"`
+	Params     map[string]string `json:"params"`
 }
 
-type eventJoinData struct {
-	ReplyToken string
+// EventUnsendData is the data of [EventUnsend].
+type EventUnsendData struct {
+	MessageID string `json:"messageId"`
 }
 
-type eventLeaveData struct {
+// EventVideoPlayCompleteData is the data of [EventVideoPlayComplete].
+type EventVideoPlayCompleteData struct {
+	ReplyToken string `json:"replyToken"`
+	TrackingID string `json:"trackingId"`
 }
 
-type eventMemberJoinedData struct {
-	ReplyToken      string
-	JoinedMemberIDs []string
+// EventBeaconData is the data of [EventBeacon].
+type EventBeaconData struct {
+	ReplyToken string `json:"replyToken"`
+	HWID       string `json:"hwid"`
+	Type       string `json:"type"` /* enter, banner or stay */
+	DM         string `json:"dm"`
 }
 
-type eventMemberLeftData struct {
-	LeftMemberIDs []string
+// EventAccountLinkData is the data of [EventAccountLink].
+type EventAccountLinkData struct {
+	ReplyToken string `json:"replyToken"`
+	Result     string `json:"result"` /* ok or failed */
+	Nonce      string `json:"nonce"`
 }
 
-type eventMessageData struct {
-	ReplyToken      string
-	MessageID       string
-	Text            string
-	QuoteToken      string
-	QuotedMessageID string
+// EventMembershipData is the data of [EventMembership].
+type EventMembershipData struct {
+	ReplyToken   string `json:"replyToken"`
+	Type         string `json:"type"` /* joined, left or renewed */
+	MembershipID int32  `json:"membershipId"`
 }
 
-type eventStickerData struct {
-	ReplyToken string
-	PackageID  string
-	StickerID  string
+// EventModuleData is the data of [EventModule].
+type EventModuleData struct {
+	Type   string   `json:"type"` /* attached or detached */
+	BotID  string   `json:"botId"`
+	Scopes []string `json:"scopes"` /* only for attached */
+	Reason string   `json:"reason"` /* only for detached */
+}
+
+// EventActivatedData is the data of [EventActivated].
+type EventActivatedData struct {
+	ChatControlExpireAt int64 `json:"chatControlExpireAt"` /* milliseconds */
+}
+
+// EventDeactivatedData is the data of [EventDeactivated].
+type EventDeactivatedData struct {
+}
+
+// EventBotSuspendedData is the data of [EventBotSuspended].
+type EventBotSuspendedData struct {
+}
+
+// EventBotResumedData is the data of [EventBotResumed].
+type EventBotResumedData struct {
 }
 
 // EventJoin is the event of a user joining a group or room.
-type EventJoin event[eventJoinData]
+type EventJoin event[EventJoinData]
 
 // EventLeave is the event of a user leaving a group or room.
-type EventLeave event[eventLeaveData]
+type EventLeave event[EventLeaveData]
 
 // EventMemberJoined is the event of a user joining a group or room.
-type EventMemberJoined event[eventMemberJoinedData]
+type EventMemberJoined event[EventMemberJoinedData]
 
 // EventMemberLeft is the event of a user leaving a group or room.
-type EventMemberLeft event[eventMemberLeftData]
+type EventMemberLeft event[EventMemberLeftData]
 
 // EventMessage is the event of a message.
-type EventMessage event[eventMessageData]
+type EventMessage event[EventMessageData]
 
 // EventSticker is the event of a sticker.
-type EventSticker event[eventStickerData]
+type EventSticker event[EventStickerData]
+
+// EventImage is the event of an image message. Download its content with [Notifier.DownloadContent].
+type EventImage event[EventImageData]
+
+// EventVideo is the event of a video message. Download its content with [Notifier.DownloadContent].
+type EventVideo event[EventVideoData]
+
+// EventAudio is the event of an audio message. Download its content with [Notifier.DownloadContent].
+type EventAudio event[EventAudioData]
+
+// EventFile is the event of a file message. Download its content with [Notifier.DownloadContent].
+type EventFile event[EventFileData]
+
+// EventLocation is the event of a location message.
+type EventLocation event[EventLocationData]
+
+// EventFollow is the event of a user adding the bot as a friend, or unblocking it.
+type EventFollow event[EventFollowData]
+
+// EventUnfollow is the event of a user blocking the bot.
+type EventUnfollow event[EventUnfollowData]
+
+// EventPostback is the event of a user performing a postback action.
+type EventPostback event[EventPostbackData]
+
+// EventUnsend is the event of a user unsending a message.
+type EventUnsend event[EventUnsendData]
+
+// EventVideoPlayComplete is the event of a user finishing watching a video message with a tracking ID.
+type EventVideoPlayComplete event[EventVideoPlayCompleteData]
+
+// EventBeacon is the event of a user entering the range of a LINE Beacon.
+type EventBeacon event[EventBeaconData]
+
+// EventAccountLink is the event of a user linking their LINE account with a provider's service account.
+type EventAccountLink event[EventAccountLinkData]
+
+// EventMembership is the event of a user joining, leaving or renewing a membership.
+type EventMembership event[EventMembershipData]
+
+// EventModule is the event of the bot being attached to or detached from a module channel.
+type EventModule event[EventModuleData]
+
+// EventActivated is the event of the module channel acquiring the chat control.
+type EventActivated event[EventActivatedData]
+
+// EventDeactivated is the event of the module channel releasing the chat control.
+type EventDeactivated event[EventDeactivatedData]
+
+// EventBotSuspended is the event of the bot being suspended.
+type EventBotSuspended event[EventBotSuspendedData]
+
+// EventBotResumed is the event of the bot being resumed from suspension.
+type EventBotResumed event[EventBotResumedData]
+
+// Time returns the time of the event.
+func (e EventJoin) Time() time.Time {
+	return millis(e.Timestamp)
+}
+
+// Time returns the time of the event.
+func (e EventLeave) Time() time.Time {
+	return millis(e.Timestamp)
+}
+
+// Time returns the time of the event.
+func (e EventMemberJoined) Time() time.Time {
+	return millis(e.Timestamp)
+}
+
+// Time returns the time of the event.
+func (e EventMemberLeft) Time() time.Time {
+	return millis(e.Timestamp)
+}
+
+// Time returns the time of the event.
+func (e EventMessage) Time() time.Time {
+	return millis(e.Timestamp)
+}
+
+// Time returns the time of the event.
+func (e EventSticker) Time() time.Time {
+	return millis(e.Timestamp)
+}
+
+// Time returns the time of the event.
+func (e EventImage) Time() time.Time {
+	return millis(e.Timestamp)
+}
+
+// Time returns the time of the event.
+func (e EventVideo) Time() time.Time {
+	return millis(e.Timestamp)
+}
+
+// Time returns the time of the event.
+func (e EventAudio) Time() time.Time {
+	return millis(e.Timestamp)
+}
+
+// Time returns the time of the event.
+func (e EventFile) Time() time.Time {
+	return millis(e.Timestamp)
+}
+
+// Time returns the time of the event.
+func (e EventLocation) Time() time.Time {
+	return millis(e.Timestamp)
+}
+
+// Time returns the time of the event.
+func (e EventFollow) Time() time.Time {
+	return millis(e.Timestamp)
+}
+
+// Time returns the time of the event.
+func (e EventUnfollow) Time() time.Time {
+	return millis(e.Timestamp)
+}
+
+// Time returns the time of the event.
+func (e EventPostback) Time() time.Time {
+	return millis(e.Timestamp)
+}
+
+// Time returns the time of the event.
+func (e EventUnsend) Time() time.Time {
+	return millis(e.Timestamp)
+}
+
+// Time returns the time of the event.
+func (e EventVideoPlayComplete) Time() time.Time {
+	return millis(e.Timestamp)
+}
+
+// Time returns the time of the event.
+func (e EventBeacon) Time() time.Time {
+	return millis(e.Timestamp)
+}
+
+// Time returns the time of the event.
+func (e EventAccountLink) Time() time.Time {
+	return millis(e.Timestamp)
+}
+
+// Time returns the time of the event.
+func (e EventMembership) Time() time.Time {
+	return millis(e.Timestamp)
+}
+
+// Time returns the time of the event.
+func (e EventModule) Time() time.Time {
+	return millis(e.Timestamp)
+}
+
+// Time returns the time of the event.
+func (e EventActivated) Time() time.Time {
+	return millis(e.Timestamp)
+}
+
+// Time returns the time of the event.
+func (e EventDeactivated) Time() time.Time {
+	return millis(e.Timestamp)
+}
+
+// Time returns the time of the event.
+func (e EventBotSuspended) Time() time.Time {
+	return millis(e.Timestamp)
+}
+
+// Time returns the time of the event.
+func (e EventBotResumed) Time() time.Time {
+	return millis(e.Timestamp)
+}
@@ -0,0 +1,75 @@
+package line
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventJSON(t *testing.T) {
+	event := EventMessage{
+		WebhookEventID: "01H00000000000000000000000",
+		Source:         Source{Type: SourceTypeGroup, UserID: "U1", GroupID: "G1"},
+		Timestamp:      1700000000000,
+		Data:           EventMessageData{ReplyToken: "r", MessageID: "1", Text: "hello"},
+	}
+
+	b, err := json.Marshal(event)
+	require.NoError(t, err)
+	require.Contains(t, string(b), `"source":{"type":"group","userId":"U1","groupId":"G1"}`)
+	require.Contains(t, string(b), `"data":{"replyToken":"r","messageId":"1","text":"hello"`)
+
+	var decoded EventMessage
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	require.Equal(t, event, decoded)
+
+	require.Error(t, json.Unmarshal([]byte(`{"source":{"type":"channel"}}`), &decoded))
+
+	require.Equal(t, time.UnixMilli(1700000000000), decoded.Time())
+	require.True(t, EventJoin{}.Time().IsZero())
+}
+
+func TestSource(t *testing.T) {
+	user := Source{Type: SourceTypeUser, UserID: "U1"}
+	group := Source{Type: SourceTypeGroup, UserID: "U1", GroupID: "G1"}
+	room := Source{Type: SourceTypeRoom, UserID: "U1", RoomID: "R1"}
+
+	require.True(t, user.IsUser())
+	require.True(t, group.IsGroup())
+	require.True(t, room.IsRoom())
+	require.False(t, group.IsUser())
+
+	require.Equal(t, "U1", user.TargetID())
+	require.Equal(t, "G1", group.TargetID())
+	require.Equal(t, "R1", room.TargetID())
+
+	buf := &bytes.Buffer{}
+	slog.New(slog.NewTextHandler(buf, nil)).Info("event", "source", group)
+	require.Contains(t, buf.String(), "source.type=group source.user_id=U1 source.group_id=G1\n")
+}
+
+func TestTextWithoutMentions(t *testing.T) {
+	bot := func(index int32) Mentionee {
+		return Mentionee{Index: index, Length: 4, Type: "user", IsSelf: true}
+	}
+
+	for _, tc := range []struct {
+		text       string
+		mentionees []Mentionee
+		expected   string
+	}{
+		{"@bot weather", []Mentionee{bot(0)}, "weather"},
+		{"@bot line1\nline2   x", []Mentionee{bot(0)}, "line1\nline2   x"},
+		{"line1\n@bot  line2\n\nline3", []Mentionee{bot(6)}, "line1\nline2\n\nline3"},
+		{"hi @bot, and\tbye  @bot", []Mentionee{bot(3), bot(18)}, "hi , and\tbye"},
+		{"a@bot b", []Mentionee{bot(1)}, "a b"},
+		{"  no mention  ", nil, "no mention"},
+	} {
+		data := EventMessageData{Text: tc.text, Mentionees: tc.mentionees}
+		require.Equal(t, tc.expected, data.TextWithoutMentions(), tc.text)
+	}
+}
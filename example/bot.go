@@ -1,6 +1,7 @@
 package example
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -19,7 +20,7 @@ func ExampleBot() {
 		log.Fatal(err)
 	}
 
-	bot.SetMessageEventHandler(func(event line.EventMessage) error {
+	bot.SetMessageEventHandler(func(ctx context.Context, event line.EventMessage) error {
 		msg := fmt.Sprintf("Hello, This is your reply: %s", event.Data.Text)
 		_, err := notifier.ReplyMessage(event.Data.ReplyToken, msg)
 		if err != nil {
@@ -1,10 +1,16 @@
 package line
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"reflect"
 
-func invoke[T any](fn func(T) error, val T) error {
-	if fn != nil {
-		return fn(val)
+	"github.com/line/line-bot-sdk-go/v8/linebot/webhook"
+)
+
+func invoke[T any](ctx context.Context, b *bot, fn func(context.Context, T) error, val T) error {
+	if handlers := handlersOf(b, fn); len(handlers) != 0 {
+		return chain(b, propagate(handlers))(ctx, val)
 	}
 	return nil
 }
@@ -16,6 +22,60 @@ func mapping[Input, Output any](input Input, fn func(Input) Output) Output {
 	return *new(Output)
 }
 
+// mappingPtr is like mapping, but returns the zero value of Output if input is nil.
+func mappingPtr[Input, Output any](input *Input, fn func(*Input) Output) Output {
+	if input != nil && fn != nil {
+		return fn(input)
+	}
+	return *new(Output)
+}
+
+// webhookEventID returns the webhook event ID of any webhook event, or empty string if it has none.
+func webhookEventID(event webhook.EventInterface) string {
+	v := reflect.Indirect(reflect.ValueOf(event))
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+
+	if f := v.FieldByName("WebhookEventId"); f.IsValid() && f.Kind() == reflect.String {
+		return f.String()
+	}
+
+	return ""
+}
+
+// webhookIsRedelivery reports whether any webhook event is redelivered by LINE.
+func webhookIsRedelivery(event webhook.EventInterface) bool {
+	v := reflect.Indirect(reflect.ValueOf(event))
+	if v.Kind() != reflect.Struct {
+		return false
+	}
+
+	if f := v.FieldByName("DeliveryContext"); f.IsValid() && f.Kind() == reflect.Pointer && !f.IsNil() {
+		if dc, ok := f.Interface().(*webhook.DeliveryContext); ok {
+			return dc.IsRedelivery
+		}
+	}
+
+	return false
+}
+
+// webhookSource returns the source of any webhook event, or nil if it has none.
+func webhookSource(event webhook.EventInterface) webhook.SourceInterface {
+	v := reflect.Indirect(reflect.ValueOf(event))
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	if f := v.FieldByName("Source"); f.IsValid() && f.Kind() == reflect.Interface && !f.IsNil() {
+		if s, ok := f.Interface().(webhook.SourceInterface); ok {
+			return s
+		}
+	}
+
+	return nil
+}
+
 // NewMention create new mention string for line message
 func NewMention(mentionKeyOrUserID string) string {
 	return fmt.Sprintf("{%s}", mentionKeyOrUserID)
@@ -8,24 +8,45 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
+	"github.com/yanun0323/line/internal"
 )
 
+type Option struct {
+	// Logger is the logger of the client. A text logger writing to stderr is used if it's nil.
+	Logger *slog.Logger
+}
+
 type LinePay struct {
 	isProduction  bool
 	channelID     string
 	channelSecret string
+	logger        *slog.Logger
 }
 
-func NewLinePay(isProduction bool, channelID string, channelSecret string) *LinePay {
+func NewLinePay(isProduction bool, channelID string, channelSecret string, opt ...Option) *LinePay {
+	option := Option{}
+	if len(opt) != 0 {
+		option = opt[0]
+	}
+
+	logger := option.Logger
+	if logger == nil {
+		logger = internal.NewLogger(os.Stderr)
+	}
+
 	return &LinePay{
 		isProduction:  isProduction,
 		channelID:     channelID,
 		channelSecret: channelSecret,
+		logger:        logger,
 	}
 }
 
@@ -92,12 +113,16 @@ func (lp *LinePay) requestOnlineAPI(ctx context.Context, req *APIRequest, parser
 	httpReq.Header.Set("X-LINE-Authorization", signature)
 	httpReq.Header.Set("X-LINE-Authorization-Nonce", nonce)
 
+	start := time.Now()
 	resp, err := client.Do(httpReq)
 	if err != nil {
+		lp.logger.ErrorContext(ctx, "request line pay api", "method", req.Method, "path", req.APIPath, "latency", time.Since(start), "error", err)
 		return "", err
 	}
 	defer resp.Body.Close()
 
+	lp.logger.DebugContext(ctx, "request line pay api", "method", req.Method, "path", req.APIPath, "status", resp.StatusCode, "latency", time.Since(start))
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return "", err
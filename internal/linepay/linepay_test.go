@@ -0,0 +1,25 @@
+package linepay
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinePayLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lp := NewLinePay(false, "channel", "secret", Option{Logger: slog.New(slog.NewTextHandler(buf, nil))})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := lp.requestOnlineAPI(ctx, &APIRequest{Method: "POST", APIPath: "/v3/payments/request", Data: map[string]any{"amount": 1}}, nil)
+	require.ErrorIs(t, err, context.Canceled)
+
+	require.Contains(t, buf.String(), `level=ERROR msg="request line pay api" method=POST path=/v3/payments/request latency=`)
+	require.Contains(t, buf.String(), `error=`)
+	require.NotContains(t, buf.String(), "\033[")
+}
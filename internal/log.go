@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// NewLogger creates the default logger which writes text logs to w.
+// The levels are colored only when w is a terminal.
+func NewLogger(w io.Writer) *slog.Logger {
+	if isTerminal(w) {
+		return slog.New(newColorHandler(w))
+	}
+
+	return slog.New(slog.NewTextHandler(w, nil))
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// colorHandler is a text handler which prints the colored level in front of each record.
+type colorHandler struct {
+	mu    *sync.Mutex
+	w     io.Writer
+	buf   *bytes.Buffer
+	inner slog.Handler
+}
+
+func newColorHandler(w io.Writer) *colorHandler {
+	buf := &bytes.Buffer{}
+	return &colorHandler{
+		mu:  &sync.Mutex{},
+		w:   w,
+		buf: buf,
+		inner: slog.NewTextHandler(buf, &slog.HandlerOptions{
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if len(groups) == 0 && a.Key == slog.LevelKey {
+					return slog.Attr{}
+				}
+				return a
+			},
+		}),
+	}
+}
+
+func (h *colorHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+func (h *colorHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.buf.Reset()
+	if err := h.inner.Handle(ctx, r); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(h.w, "%s%s%s %s", levelColor(r.Level), r.Level, ColorReset, h.buf.Bytes())
+	return err
+}
+
+func (h *colorHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &colorHandler{mu: h.mu, w: h.w, buf: h.buf, inner: h.inner.WithAttrs(attrs)}
+}
+
+func (h *colorHandler) WithGroup(name string) slog.Handler {
+	return &colorHandler{mu: h.mu, w: h.w, buf: h.buf, inner: h.inner.WithGroup(name)}
+}
+
+func levelColor(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return ColorRed
+	case level >= slog.LevelWarn:
+		return ColorYellow
+	case level >= slog.LevelInfo:
+		return ColorGreen
+	default:
+		return ColorGray
+	}
+}
@@ -0,0 +1,37 @@
+package internal
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	NewLogger(buf).Info("hello", "key", "value")
+	require.Contains(t, buf.String(), `level=INFO msg=hello key=value`)
+	require.NotContains(t, buf.String(), "\033[")
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "log"))
+	require.NoError(t, err)
+	defer f.Close()
+	require.False(t, isTerminal(f))
+	require.False(t, isTerminal(buf))
+
+	buf.Reset()
+	NewLogger(buf).Debug("hidden")
+	require.Empty(t, buf.String())
+}
+
+func TestColorHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(newColorHandler(buf))
+	logger.Error("failed", "error", "boom")
+	require.Contains(t, buf.String(), ColorRed)
+	require.Contains(t, buf.String(), `msg=failed error=boom`)
+	require.NotContains(t, buf.String(), "level=")
+}
@@ -0,0 +1,90 @@
+package line
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrBotClosed is returned when the bot has been shut down and no longer accepts webhooks.
+	ErrBotClosed = errors.New("line: bot closed")
+
+	// ErrShutdownTimeout is returned by Shutdown when the context is done before all handlers finish.
+	ErrShutdownTimeout = errors.New("line: shutdown timeout")
+)
+
+// inflight tracks the handlers which are currently running.
+type inflight struct {
+	mu      sync.Mutex
+	seq     uint64
+	running map[uint64]string
+	idle    chan struct{}
+}
+
+func newInflight() *inflight {
+	idle := make(chan struct{})
+	close(idle)
+
+	return &inflight{
+		running: map[uint64]string{},
+		idle:    idle,
+	}
+}
+
+// add registers a running handler described by name, and returns the function to mark it done.
+func (f *inflight) add(name string) (done func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.running) == 0 {
+		f.idle = make(chan struct{})
+	}
+
+	f.seq++
+	id := f.seq
+	f.running[id] = name
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+
+			delete(f.running, id)
+			if len(f.running) == 0 {
+				close(f.idle)
+			}
+		})
+	}
+}
+
+// wait returns a channel which is closed when there is no running handler.
+func (f *inflight) wait() <-chan struct{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.idle
+}
+
+// unfinished returns the names of the running handlers.
+func (f *inflight) unfinished() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	names := make([]string, 0, len(f.running))
+	for _, name := range f.running {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// handlerName describes a handler invocation for the shutdown report.
+func handlerName(eventType string, webhookEventID string) string {
+	return fmt.Sprintf("%s(%s)", strings.TrimPrefix(eventType, "webhook."), webhookEventID)
+}
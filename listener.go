@@ -0,0 +1,141 @@
+package line
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrStopPropagation can be returned by a handler to stop the next handlers of the event from being called.
+// The event is treated as handled successfully.
+var ErrStopPropagation = errors.New("line: stop propagation")
+
+// OnOption is the option for the handlers added by [On].
+type OnOption struct {
+	// Priority decides the order of the handlers. Handlers with higher priority are called first,
+	// and handlers with the same priority are called in the order they are added.
+	Priority int
+}
+
+type listener struct {
+	priority int
+	handler  any
+}
+
+// Subscription is the handle of a handler added by [On].
+type Subscription struct {
+	bot  *bot
+	typ  reflect.Type
+	l    *listener
+	once sync.Once
+}
+
+// On adds a handler of the event T, such as [EventMessage], and returns its subscription which can remove it.
+//
+// Unlike the Set*EventHandler methods of [Bot], it doesn't replace the other handlers.
+// The handler set by Set*EventHandler is called first, then the handlers added by [On] by their priority,
+// until one returns an error. Return [ErrStopPropagation] to skip the rest handlers without an error.
+// The middlewares wrap all the handlers of the event once.
+//
+// Use [webhook.EventInterface] as T to handle the events which the library doesn't support yet, like [Bot.SetRawEventHandler].
+// It does nothing if b isn't created by [NewBot], and the returned subscription removes nothing.
+//
+// # Example:
+//
+//	sub := line.On(bot, func(ctx context.Context, event line.EventMessage) error {
+//		return audit(ctx, event)
+//	}, line.OnOption{Priority: 10})
+//
+//	// detach the handler
+//	sub.Remove()
+func On[T any](b Bot, fn Handler[T], opt ...OnOption) *Subscription {
+	option := OnOption{}
+	if len(opt) != 0 {
+		option = opt[0]
+	}
+
+	c, ok := b.(botCore)
+	if !ok {
+		return &Subscription{}
+	}
+
+	core := c.core()
+	typ := reflect.TypeFor[T]()
+	l := &listener{priority: option.Priority, handler: fn}
+
+	core.listenersMu.Lock()
+	defer core.listenersMu.Unlock()
+
+	// copy on write, so that the running events keep their snapshot.
+	listeners := append(append([]*listener{}, core.listeners[typ]...), l)
+	sort.SliceStable(listeners, func(i, j int) bool {
+		return listeners[i].priority > listeners[j].priority
+	})
+	core.listeners[typ] = listeners
+
+	return &Subscription{bot: core, typ: typ, l: l}
+}
+
+// Remove removes the handler from the bot. The events being handled aren't affected.
+// It's safe to call Remove more than once.
+func (s *Subscription) Remove() {
+	s.once.Do(func() {
+		if s.bot == nil {
+			return
+		}
+
+		s.bot.listenersMu.Lock()
+		defer s.bot.listenersMu.Unlock()
+
+		listeners := make([]*listener, 0, len(s.bot.listeners[s.typ]))
+		for _, l := range s.bot.listeners[s.typ] {
+			if l != s.l {
+				listeners = append(listeners, l)
+			}
+		}
+
+		if len(listeners) == 0 {
+			delete(s.bot.listeners, s.typ)
+			return
+		}
+
+		s.bot.listeners[s.typ] = listeners
+	})
+}
+
+// handlersOf returns the handler set by Set*EventHandler followed by the handlers of the event T added by [On].
+func handlersOf[T any](b *bot, fn func(context.Context, T) error) []Handler[T] {
+	b.listenersMu.RLock()
+	listeners := b.listeners[reflect.TypeFor[T]()]
+	b.listenersMu.RUnlock()
+
+	handlers := make([]Handler[T], 0, len(listeners)+1)
+	if fn != nil {
+		handlers = append(handlers, fn)
+	}
+
+	for _, l := range listeners {
+		handlers = append(handlers, l.handler.(Handler[T]))
+	}
+
+	return handlers
+}
+
+// propagate calls the handlers in order until one returns an error.
+func propagate[T any](handlers []Handler[T]) Handler[T] {
+	return func(ctx context.Context, event T) error {
+		for _, h := range handlers {
+			if err := h(ctx, event); err != nil {
+				if errors.Is(err, ErrStopPropagation) {
+					return nil
+				}
+				return err
+			}
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,63 @@
+package line
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOn(t *testing.T) {
+	b, err := NewBot(testChannelSecret, BotOption{DisableDeduplication: true, ErrorPolicy: ErrorPolicyFailOnError})
+	require.NoError(t, err)
+
+	var trace []string
+	record := func(name string, err error) Handler[EventMessage] {
+		return func(ctx context.Context, event EventMessage) error {
+			trace = append(trace, name)
+			return err
+		}
+	}
+
+	middleware := 0
+	UseFor(b, func(next Handler[EventMessage]) Handler[EventMessage] {
+		return func(ctx context.Context, event EventMessage) error {
+			middleware++
+			return next(ctx, event)
+		}
+	})
+
+	b.SetMessageEventHandler(record("set", nil))
+	first := On(b, record("first", nil))
+	On(b, record("second", nil))
+	On(b, record("priority", nil), OnOption{Priority: 1})
+
+	handle := func() int {
+		trace = nil
+		rec := httptest.NewRecorder()
+		b.ServeHTTP(rec, newTestRequest(t, fmt.Sprintf(testMessageEvent, 0)))
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusOK, handle())
+	require.Equal(t, []string{"set", "priority", "first", "second"}, trace)
+	require.Equal(t, 1, middleware)
+
+	first.Remove()
+	first.Remove()
+	require.Equal(t, http.StatusOK, handle())
+	require.Equal(t, []string{"set", "priority", "second"}, trace)
+
+	stop := On(b, record("stop", ErrStopPropagation), OnOption{Priority: 2})
+	require.Equal(t, http.StatusOK, handle())
+	require.Equal(t, []string{"set", "stop"}, trace)
+	stop.Remove()
+
+	On(b, record("fail", errors.New("fail")), OnOption{Priority: 2})
+	require.Equal(t, http.StatusInternalServerError, handle())
+	require.Equal(t, []string{"set", "fail"}, trace)
+}
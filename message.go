@@ -0,0 +1,169 @@
+package line
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
+	"github.com/pkg/errors"
+)
+
+// ErrTooManyMessages is returned when more messages are sent at once than LINE accepts.
+var ErrTooManyMessages = errors.New("line: too many messages")
+
+// messagesMaxCount is the max number of messages sent by a reply or push accepted by LINE.
+const messagesMaxCount = 5
+
+// Message is a message to be sent by [Notifier.ReplyMessages] or [Notifier.SendMessages].
+//
+// Create it with NewTextMessage, NewStickerMessage, NewImageMessage, NewVideoMessage, NewAudioMessage,
+// NewLocationMessage, NewImagemapMessage, NewTemplateMessage, NewFlexMessage, or NewRawMessage for the others.
+type Message interface {
+	messagingAPI() messaging_api.MessageInterface
+}
+
+// SentMessage is a message sent by the notifier.
+type SentMessage struct {
+	// ID is the ID of the sent message.
+	ID LineMessageID
+	// QuoteToken is the token to quote the sent message. It's empty for the messages which can't be quoted.
+	QuoteToken string
+}
+
+type message struct {
+	m messaging_api.MessageInterface
+}
+
+func (m message) messagingAPI() messaging_api.MessageInterface {
+	return m.m
+}
+
+// NewTextMessage creates a text message, which can have mentions and emojis, see [TextBuilder].
+func NewTextMessage(text string, opt ...NotifyMessageOption) Message {
+	return message{newTextMessage(text, opt...)}
+}
+
+// NewStickerMessage creates a sticker message. Only NotifyMessageOption.QuoteToken is used.
+func NewStickerMessage(sticker Sticker, opt ...NotifyMessageOption) Message {
+	return message{newStickerMessage(sticker, opt...)}
+}
+
+// NewImageMessage creates an image message. The URLs must be HTTPS, and the preview image is shown in the chat.
+func NewImageMessage(originalContentURL, previewImageURL string) Message {
+	return message{messaging_api.ImageMessage{
+		Message:            messaging_api.Message{Type: "image"},
+		OriginalContentUrl: originalContentURL,
+		PreviewImageUrl:    previewImageURL,
+	}}
+}
+
+// NewVideoMessage creates a video message. The URLs must be HTTPS.
+// A non-empty trackingID makes LINE send [EventVideoPlayComplete] when a user finishes watching it.
+func NewVideoMessage(originalContentURL, previewImageURL, trackingID string) Message {
+	return message{messaging_api.VideoMessage{
+		Message:            messaging_api.Message{Type: "video"},
+		OriginalContentUrl: originalContentURL,
+		PreviewImageUrl:    previewImageURL,
+		TrackingId:         trackingID,
+	}}
+}
+
+// NewAudioMessage creates an audio message. The URL must be HTTPS.
+func NewAudioMessage(originalContentURL string, duration time.Duration) Message {
+	return message{messaging_api.AudioMessage{
+		Message:            messaging_api.Message{Type: "audio"},
+		OriginalContentUrl: originalContentURL,
+		Duration:           duration.Milliseconds(),
+	}}
+}
+
+// NewLocationMessage creates a location message.
+func NewLocationMessage(location Location) Message {
+	return message{newLocationMessage(location)}
+}
+
+// NewImagemapMessage creates an imagemap message, which is an image with tappable areas.
+func NewImagemapMessage(imagemap messaging_api.ImagemapMessage) Message {
+	imagemap.Type = "imagemap"
+	return message{imagemap}
+}
+
+// NewTemplateMessage creates a template message, such as buttons, confirm, carousel or image carousel.
+// altText is shown in the notifications and the chat list.
+func NewTemplateMessage(altText string, template messaging_api.TemplateInterface) Message {
+	return message{messaging_api.TemplateMessage{
+		Message:  messaging_api.Message{Type: "template"},
+		AltText:  altText,
+		Template: template,
+	}}
+}
+
+// NewFlexMessage creates a flex message with a bubble or carousel container.
+// altText is shown in the notifications and the chat list.
+func NewFlexMessage(altText string, contents messaging_api.FlexContainerInterface) Message {
+	return message{messaging_api.FlexMessage{
+		Message:  messaging_api.Message{Type: "flex"},
+		AltText:  altText,
+		Contents: contents,
+	}}
+}
+
+// NewFlexMessageJSON creates a flex message with the container in JSON, e.g. designed in the Flex Message Simulator.
+//
+// The JSON is sent as it is, because decoding it into the messaging API types adds zero values like "flex":0, which change the layout.
+func NewFlexMessageJSON(altText string, contents []byte) (Message, error) {
+	var container struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(contents, &container); err != nil {
+		return nil, errors.Errorf("unmarshal flex container, err: %+v", err)
+	}
+
+	if container.Type != "bubble" && container.Type != "carousel" {
+		return nil, errors.Errorf("unknown flex container type: %q", container.Type)
+	}
+
+	return NewFlexMessage(altText, rawFlexContainer{typ: container.Type, raw: contents}), nil
+}
+
+// rawFlexContainer is a flex container which is encoded as its original JSON.
+type rawFlexContainer struct {
+	typ string
+	raw json.RawMessage
+}
+
+func (c rawFlexContainer) GetType() string {
+	return c.typ
+}
+
+func (c rawFlexContainer) MarshalJSON() ([]byte, error) {
+	return c.raw, nil
+}
+
+// NewRawMessage creates a message from any message of the messaging API,
+// e.g. to set the quick reply or the sender, or to send the messages which the library doesn't support yet.
+// The Type of the embedded messaging_api.Message must be set.
+func NewRawMessage(m messaging_api.MessageInterface) Message {
+	return message{m}
+}
+
+// messagingAPIMessages converts the messages for the messaging API, and checks the number of them.
+func messagingAPIMessages(messages []Message) ([]messaging_api.MessageInterface, error) {
+	if len(messages) == 0 {
+		return nil, errors.New("no message to send")
+	}
+
+	if len(messages) > messagesMaxCount {
+		return nil, errors.Wrapf(ErrTooManyMessages, "%d messages, which exceeds %d", len(messages), messagesMaxCount)
+	}
+
+	result := make([]messaging_api.MessageInterface, len(messages))
+	for i, m := range messages {
+		if m == nil {
+			return nil, errors.Errorf("message %d is nil", i)
+		}
+		result[i] = m.messagingAPI()
+	}
+
+	return result, nil
+}
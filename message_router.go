@@ -0,0 +1,166 @@
+package line
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// MessageMatch is the result of a matched route of a [MessageRouter].
+type MessageMatch struct {
+	// Text is the trimmed text of the message.
+	Text string
+	// Rest is the text after the prefix for prefix routes.
+	Rest string
+	// Groups is the submatches for regexp routes, where Groups[0] is the whole match.
+	Groups []string
+	// Captures is the named submatches for regexp routes.
+	Captures map[string]string
+}
+
+// MessageRouteOption is the option for a route of a [MessageRouter].
+type MessageRouteOption struct {
+	// Priority decides the order of the routes. Routes with higher priority are matched first,
+	// and routes with the same priority are matched in the order they are added.
+	Priority int
+	// Fallthrough makes the router keep matching the next routes after this route is handled.
+	Fallthrough bool
+	// IgnoreCase makes exact and prefix routes match case-insensitively.
+	IgnoreCase bool
+}
+
+// MessageRouteHandler is the handler of a route of a [MessageRouter].
+type MessageRouteHandler func(ctx context.Context, event EventMessage, match MessageMatch) error
+
+type messageRoute struct {
+	option  MessageRouteOption
+	match   func(event EventMessage, text string) (MessageMatch, bool)
+	handler MessageRouteHandler
+}
+
+// MessageRouter routes text message events by exact text, prefix, regexp or a custom predicate.
+//
+// # Example:
+//
+//	router := line.NewMessageRouter()
+//	router.Exact("hi", greet, line.MessageRouteOption{IgnoreCase: true})
+//	router.Regexp(regexp.MustCompile(`^weather in (?P<city>\w+)$`), func(ctx context.Context, event line.EventMessage, match line.MessageMatch) error {
+//		return weather(ctx, match.Captures["city"])
+//	})
+//
+//	bot.SetMessageEventHandler(router.HandleEvent)
+type MessageRouter struct {
+	routes   []*messageRoute
+	fallback func(ctx context.Context, event EventMessage) error
+}
+
+// NewMessageRouter creates a new message router.
+func NewMessageRouter() *MessageRouter {
+	return &MessageRouter{}
+}
+
+// Exact adds a route for the messages whose trimmed text equals exact.
+func (r *MessageRouter) Exact(exact string, fn MessageRouteHandler, opt ...MessageRouteOption) {
+	option := messageRouteOption(opt...)
+	r.add(option, fn, func(_ EventMessage, text string) (MessageMatch, bool) {
+		if text != exact && !(option.IgnoreCase && strings.EqualFold(text, exact)) {
+			return MessageMatch{}, false
+		}
+		return MessageMatch{Text: text}, true
+	})
+}
+
+// Prefix adds a route for the messages whose trimmed text starts with prefix.
+// The text after the prefix is passed as [MessageMatch.Rest].
+func (r *MessageRouter) Prefix(prefix string, fn MessageRouteHandler, opt ...MessageRouteOption) {
+	option := messageRouteOption(opt...)
+	r.add(option, fn, func(_ EventMessage, text string) (MessageMatch, bool) {
+		if len(text) < len(prefix) {
+			return MessageMatch{}, false
+		}
+
+		head := text[:len(prefix)]
+		if head != prefix && !(option.IgnoreCase && strings.EqualFold(head, prefix)) {
+			return MessageMatch{}, false
+		}
+
+		return MessageMatch{Text: text, Rest: strings.TrimSpace(text[len(prefix):])}, true
+	})
+}
+
+// Regexp adds a route for the messages whose trimmed text matches re.
+// The submatches are passed as [MessageMatch.Groups], and the named ones as [MessageMatch.Captures].
+func (r *MessageRouter) Regexp(re *regexp.Regexp, fn MessageRouteHandler, opt ...MessageRouteOption) {
+	r.add(messageRouteOption(opt...), fn, func(_ EventMessage, text string) (MessageMatch, bool) {
+		groups := re.FindStringSubmatch(text)
+		if groups == nil {
+			return MessageMatch{}, false
+		}
+
+		captures := map[string]string{}
+		for i, name := range re.SubexpNames() {
+			if len(name) != 0 {
+				captures[name] = groups[i]
+			}
+		}
+
+		return MessageMatch{Text: text, Groups: groups, Captures: captures}, true
+	})
+}
+
+// Match adds a route for the messages which satisfy the predicate.
+func (r *MessageRouter) Match(predicate func(event EventMessage) bool, fn MessageRouteHandler, opt ...MessageRouteOption) {
+	r.add(messageRouteOption(opt...), fn, func(event EventMessage, text string) (MessageMatch, bool) {
+		return MessageMatch{Text: text}, predicate(event)
+	})
+}
+
+// Fallback sets the handler for the messages which match no route, or only routes with [MessageRouteOption.Fallthrough].
+func (r *MessageRouter) Fallback(fn func(ctx context.Context, event EventMessage) error) {
+	r.fallback = fn
+}
+
+func (r *MessageRouter) add(option MessageRouteOption, fn MessageRouteHandler, match func(EventMessage, string) (MessageMatch, bool)) {
+	r.routes = append(r.routes, &messageRoute{option: option, match: match, handler: fn})
+	sort.SliceStable(r.routes, func(i, j int) bool {
+		return r.routes[i].option.Priority > r.routes[j].option.Priority
+	})
+}
+
+// HandleEvent routes the text message event. It can be set as the message event handler of [Bot].
+//
+// The matched routes are handled in order until one without [MessageRouteOption.Fallthrough] is handled,
+// or one returns an error.
+func (r *MessageRouter) HandleEvent(ctx context.Context, event EventMessage) error {
+	text := strings.TrimSpace(event.Data.Text)
+
+	for _, route := range r.routes {
+		match, ok := route.match(event, text)
+		if !ok {
+			continue
+		}
+
+		if err := route.handler(ctx, event, match); err != nil {
+			return err
+		}
+
+		if !route.option.Fallthrough {
+			return nil
+		}
+	}
+
+	if r.fallback != nil {
+		return r.fallback(ctx, event)
+	}
+
+	return nil
+}
+
+func messageRouteOption(opt ...MessageRouteOption) MessageRouteOption {
+	if len(opt) != 0 {
+		return opt[0]
+	}
+
+	return MessageRouteOption{}
+}
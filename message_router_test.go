@@ -0,0 +1,61 @@
+package line
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageRouter(t *testing.T) {
+	var handled []string
+	var got MessageMatch
+	record := func(name string) MessageRouteHandler {
+		return func(ctx context.Context, event EventMessage, match MessageMatch) error {
+			handled = append(handled, name)
+			got = match
+			return nil
+		}
+	}
+
+	router := NewMessageRouter()
+	router.Exact("hi", record("exact"), MessageRouteOption{IgnoreCase: true})
+	router.Prefix("echo", record("prefix"))
+	router.Regexp(regexp.MustCompile(`^weather in (?P<city>\w+)$`), record("regexp"))
+	router.Match(func(event EventMessage) bool {
+		return strings.Contains(event.Data.Text, "help")
+	}, record("audit"), MessageRouteOption{Priority: 10, Fallthrough: true})
+	router.Fallback(func(ctx context.Context, event EventMessage) error {
+		handled = append(handled, "fallback")
+		return nil
+	})
+
+	handle := func(text string) []string {
+		handled = nil
+		require.NoError(t, router.HandleEvent(context.Background(), newTextEvent(text)))
+		return handled
+	}
+
+	require.Equal(t, []string{"exact"}, handle("  HI "))
+	require.Equal(t, "HI", got.Text)
+
+	require.Equal(t, []string{"prefix"}, handle("echo  hello"))
+	require.Equal(t, "hello", got.Rest)
+
+	require.Equal(t, []string{"regexp"}, handle("weather in Tokyo"))
+	require.Equal(t, map[string]string{"city": "Tokyo"}, got.Captures)
+	require.Equal(t, []string{"weather in Tokyo", "Tokyo"}, got.Groups)
+
+	require.Equal(t, []string{"audit", "prefix"}, handle("echo help"))
+	require.Equal(t, []string{"audit", "fallback"}, handle("help"))
+	require.Equal(t, []string{"fallback"}, handle("bye"))
+
+	errStop := errors.New("stop")
+	router.Match(func(EventMessage) bool { return true }, func(ctx context.Context, event EventMessage, match MessageMatch) error {
+		return errStop
+	}, MessageRouteOption{Priority: 20})
+	require.ErrorIs(t, router.HandleEvent(context.Background(), newTextEvent("hi")), errStop)
+}
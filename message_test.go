@@ -0,0 +1,67 @@
+package line
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
+	"github.com/stretchr/testify/require"
+	"github.com/yanun0323/line/internal"
+)
+
+func TestMessages(t *testing.T) {
+	flex, err := NewFlexMessageJSON("menu", []byte(`{"type":"bubble","body":{"type":"box","layout":"vertical","contents":[{"type":"text","text":"hi"}]}}`))
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		message  Message
+		expected string
+	}{
+		{NewTextMessage("hi", NotifyMessageOption{QuoteToken: "q"}), `{"type":"textV2","text":"hi","quoteToken":"q"}`},
+		{NewImageMessage("https://a/o.jpg", "https://a/p.jpg"), `{"type":"image","originalContentUrl":"https://a/o.jpg","previewImageUrl":"https://a/p.jpg"}`},
+		{NewVideoMessage("https://a/o.mp4", "https://a/p.jpg", "t1"), `{"type":"video","originalContentUrl":"https://a/o.mp4","previewImageUrl":"https://a/p.jpg","trackingId":"t1"}`},
+		{NewAudioMessage("https://a/o.m4a", 1500*time.Millisecond), `{"type":"audio","originalContentUrl":"https://a/o.m4a","duration":1500}`},
+		{NewTemplateMessage("confirm?", messaging_api.ConfirmTemplate{
+			Template: messaging_api.Template{Type: "confirm"},
+			Text:     "sure?",
+			Actions: []messaging_api.ActionInterface{
+				messaging_api.MessageAction{Action: messaging_api.Action{Type: "message"}, Label: "yes", Text: "yes"},
+			},
+		}), `{"type":"template","altText":"confirm?","template":{"type":"confirm","text":"sure?","actions":[{"type":"message","label":"yes","text":"yes"}]}}`},
+		{flex, `{"type":"flex","altText":"menu","contents":{"type":"bubble","body":{"type":"box","layout":"vertical","contents":[{"type":"text","text":"hi"}]}}}`},
+	} {
+		b, err := json.Marshal(tc.message.messagingAPI())
+		require.NoError(t, err)
+		require.JSONEq(t, tc.expected, string(b))
+	}
+}
+
+func TestNotifierMessages(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		_, _ = w.Write([]byte(`{"sentMessages":[{"id":"M1","quoteToken":"q1"},{"id":"M2"}]}`))
+	}))
+	defer server.Close()
+
+	api, err := messaging_api.NewMessagingApiAPI("token", messaging_api.WithEndpoint(server.URL))
+	require.NoError(t, err)
+	n := &lineNotifier{bot: api, logger: internal.NewLogger(io.Discard)}
+
+	sent, err := n.ReplyMessages("r", NewTextMessage("hi"), NewStickerMessage(Sticker{PackageID: "446", StickerID: "1988"}))
+	require.NoError(t, err)
+	require.Equal(t, []SentMessage{{ID: "M1", QuoteToken: "q1"}, {ID: "M2"}}, sent)
+	require.True(t, strings.Contains(body, `"type":"sticker"`))
+
+	_, err = n.SendMessages("U1", make([]Message, 6)...)
+	require.ErrorIs(t, err, ErrTooManyMessages)
+
+	_, err = n.SendMessages("U1")
+	require.Error(t, err)
+}
@@ -0,0 +1,155 @@
+package line
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/yanun0323/line/internal"
+)
+
+// ErrPanic is returned when an event handler panics.
+var ErrPanic = errors.New("line: handler panic")
+
+// PanicError is the error of a recovered panic, which wraps [ErrPanic].
+//
+// Its message is kept on one line, and the stack trace is kept in Stack.
+type PanicError struct {
+	// Event is the type name of the event, e.g. "EventMessage".
+	Event string
+	// Value is the value passed to panic.
+	Value any
+	// Stack is the stack trace of the goroutine where the panic is recovered.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%s: %s: %v", ErrPanic, e.Event, e.Value)
+}
+
+func (e *PanicError) Unwrap() error {
+	return ErrPanic
+}
+
+// Handler is the handler of the event T.
+type Handler[T any] func(context.Context, T) error
+
+// Middleware wraps the handler of the event T, e.g. to log, recover or authorize the event before calling next.
+type Middleware[T any] func(next Handler[T]) Handler[T]
+
+// UseFor adds middlewares which wrap the handlers of the event T only, such as [EventMessage].
+//
+// They run inside the global middlewares added by [Bot.Use], in the order they are added.
+// It does nothing if b isn't created by [NewBot].
+func UseFor[T any](b Bot, middlewares ...Middleware[T]) {
+	c, ok := b.(botCore)
+	if !ok {
+		return
+	}
+
+	core := c.core()
+	typ := reflect.TypeFor[T]()
+
+	core.middlewaresMu.Lock()
+	defer core.middlewaresMu.Unlock()
+
+	// copy on write, so that the running events keep their snapshot.
+	mws := append([]any{}, core.middlewares[typ]...)
+	for _, mw := range middlewares {
+		mws = append(mws, mw)
+	}
+	core.middlewares[typ] = mws
+}
+
+// chain wraps the handler with the middlewares of the event T and the global middlewares.
+//
+// Panics in the handler are recovered before the middlewares, so that they can see the error, and so are panics in the middlewares.
+func chain[T any](b *bot, fn Handler[T]) Handler[any] {
+	b.middlewaresMu.RLock()
+	mws := b.middlewares[reflect.TypeFor[T]()]
+	globals := b.globalMiddlewares
+	b.middlewaresMu.RUnlock()
+
+	h := recovery(fn)
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i].(Middleware[T])(h)
+	}
+
+	var g Handler[any] = func(ctx context.Context, val any) error {
+		return h(ctx, val.(T))
+	}
+	for i := len(globals) - 1; i >= 0; i-- {
+		g = globals[i](g)
+	}
+
+	return recovery(g)
+}
+
+// Recovery returns a middleware which recovers the handler from panics, and returns a [*PanicError] with the stack trace.
+func Recovery() Middleware[any] {
+	return recovery[any]
+}
+
+func recovery[T any](next Handler[T]) Handler[T] {
+	return func(ctx context.Context, event T) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = &PanicError{Event: eventName(event), Value: r, Stack: debug.Stack()}
+			}
+		}()
+
+		return next(ctx, event)
+	}
+}
+
+// Logging returns a middleware which logs every handled event with its latency and error.
+// A text logger writing to stderr is used if logger is nil.
+func Logging(logger *slog.Logger) Middleware[any] {
+	if logger == nil {
+		logger = internal.NewLogger(os.Stderr)
+	}
+
+	return func(next Handler[any]) Handler[any] {
+		return func(ctx context.Context, event any) error {
+			id, _ := WebhookEventIDFromContext(ctx)
+			src, _ := SourceFromContext(ctx)
+			attrs := []any{"event", eventName(event), "webhook_event_id", id, "source", src}
+
+			start := time.Now()
+			err := next(ctx, event)
+			attrs = append(attrs, "latency", time.Since(start))
+			if err != nil {
+				logger.ErrorContext(ctx, "handle event", append(attrs, "error", err)...)
+			} else {
+				logger.InfoContext(ctx, "handle event", attrs...)
+			}
+
+			return err
+		}
+	}
+}
+
+// Timing returns a middleware which reports how long the handler takes for every event.
+func Timing(report func(ctx context.Context, event any, elapsed time.Duration)) Middleware[any] {
+	return func(next Handler[any]) Handler[any] {
+		return func(ctx context.Context, event any) error {
+			start := time.Now()
+			defer func() {
+				report(ctx, event, time.Since(start))
+			}()
+
+			return next(ctx, event)
+		}
+	}
+}
+
+// eventName returns the type name of the event, e.g. "EventMessage".
+func eventName(event any) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", event), "line.")
+}
@@ -0,0 +1,97 @@
+package line
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	b, err := NewBot(testChannelSecret)
+	require.NoError(t, err)
+
+	var (
+		trace   []string
+		elapsed time.Duration
+		handled error
+	)
+
+	b.Use(func(next Handler[any]) Handler[any] {
+		return func(ctx context.Context, event any) error {
+			trace = append(trace, "global:"+eventName(event))
+			handled = next(ctx, event)
+			return handled
+		}
+	})
+	b.Use(Timing(func(ctx context.Context, event any, d time.Duration) {
+		elapsed = d
+	}))
+	UseFor(b, func(next Handler[EventMessage]) Handler[EventMessage] {
+		return func(ctx context.Context, event EventMessage) error {
+			trace = append(trace, "message:"+event.Data.Text)
+			return next(ctx, event)
+		}
+	})
+	b.SetMessageEventHandler(func(ctx context.Context, event EventMessage) error {
+		trace = append(trace, "handler")
+		time.Sleep(time.Millisecond)
+		panic("boom")
+	})
+
+	rec := httptest.NewRecorder()
+	b.ServeHTTP(rec, newTestRequest(t, fmt.Sprintf(testMessageEvent, 0)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	require.Equal(t, []string{"global:EventMessage", "message:hello", "handler"}, trace)
+	require.ErrorIs(t, handled, ErrPanic)
+	require.EqualError(t, handled, "line: handler panic: EventMessage: boom")
+
+	var panicErr *PanicError
+	require.ErrorAs(t, handled, &panicErr)
+	require.Equal(t, "boom", panicErr.Value)
+	require.Contains(t, string(panicErr.Stack), "TestMiddleware")
+	require.Greater(t, elapsed, time.Duration(0))
+}
+
+func TestMiddlewareConcurrentUse(t *testing.T) {
+	b, err := NewBot(testChannelSecret, BotOption{DisableDeduplication: true})
+	require.NoError(t, err)
+	b.SetMessageEventHandler(func(ctx context.Context, event EventMessage) error {
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range 100 {
+			b.Use(Timing(func(context.Context, any, time.Duration) {}))
+			UseFor(b, func(next Handler[EventMessage]) Handler[EventMessage] { return next })
+		}
+	}()
+
+	for range 100 {
+		rec := httptest.NewRecorder()
+		b.ServeHTTP(rec, newTestRequest(t, fmt.Sprintf(testMessageEvent, 0)))
+		require.Equal(t, http.StatusOK, rec.Code)
+	}
+	wg.Wait()
+}
+
+// fakeBot is a [Bot] implemented outside of NewBot, like the fakes in tests of the users.
+type fakeBot struct {
+	Bot
+}
+
+func TestMiddlewareFakeBot(t *testing.T) {
+	var b Bot = fakeBot{}
+
+	UseFor(b, func(next Handler[EventMessage]) Handler[EventMessage] { return next })
+	On(b, func(ctx context.Context, event EventMessage) error { return nil }).Remove()
+}
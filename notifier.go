@@ -1,13 +1,26 @@
 package line
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
 
 	"github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
 	"github.com/pkg/errors"
 	"github.com/yanun0323/line/internal"
 )
 
+// ErrContentTranscodingFailed is returned when LINE fails to prepare the content of a video or audio message.
+var ErrContentTranscodingFailed = errors.New("line: content transcoding failed")
+
+const (
+	contentPollMinInterval = 500 * time.Millisecond
+	contentPollMaxInterval = 5 * time.Second
+)
+
 // LineMessageID is the ID of the message.
 type LineMessageID string
 
@@ -17,20 +30,91 @@ type NotifyMessageOption struct {
 	QuoteToken string
 	// MentionUserID is the user ID of the message to be mentioned
 	MentionUserID map[string]string
+	// MentionAll is the keys made by [NewMention] to be replaced by a mention of all members
+	MentionAll []string
+	// Emoji is the LINE emojis of the message, which replace the keys made by [NewMention] like mentions
+	Emoji map[string]Emoji
+}
+
+// NotifierOption is the option for the notifier.
+type NotifierOption struct {
+	// Logger is the logger of the notifier. A text logger writing to stderr is used if it's nil, which is colored on terminals.
+	Logger *slog.Logger
+}
+
+// Location is the location to be sent.
+type Location struct {
+	// Title is the title of the location. Max 100 characters.
+	Title string
+	// Address is the address of the location. Max 100 characters.
+	Address string
+	// Latitude is the latitude of the location.
+	Latitude float64
+	// Longitude is the longitude of the location.
+	Longitude float64
+}
+
+// Sticker is the sticker to be sent. See https://developers.line.biz/en/docs/messaging-api/sticker-list/ for the available stickers.
+type Sticker struct {
+	// PackageID is the package ID of the sticker set.
+	PackageID string
+	// StickerID is the ID of the sticker.
+	StickerID string
 }
 
 // Notifier is the interface for the notifier.
+//
+// Each method has a variant suffixed with Context, which cancels the request to LINE when ctx is done.
 type Notifier interface {
 	// ReplyMessage [FREE] reply message to user
 	ReplyMessage(replyToken, text string, opt ...NotifyMessageOption) (LineMessageID, error)
+	ReplyMessageContext(ctx context.Context, replyToken, text string, opt ...NotifyMessageOption) (LineMessageID, error)
 
 	// SendMessage [PAID] send message to user
 	SendMessage(targetID, text string, opt ...NotifyMessageOption) (LineMessageID, error)
+	SendMessageContext(ctx context.Context, targetID, text string, opt ...NotifyMessageOption) (LineMessageID, error)
+
+	// ReplyMessages [FREE] reply up to 5 messages of any type to user, and returns every sent message
+	ReplyMessages(replyToken string, messages ...Message) ([]SentMessage, error)
+	ReplyMessagesContext(ctx context.Context, replyToken string, messages ...Message) ([]SentMessage, error)
+
+	// SendMessages [PAID] send up to 5 messages of any type to user, and returns every sent message
+	SendMessages(targetID string, messages ...Message) ([]SentMessage, error)
+	SendMessagesContext(ctx context.Context, targetID string, messages ...Message) ([]SentMessage, error)
+
+	// ReplyLocation [FREE] reply location message to user
+	ReplyLocation(replyToken string, location Location) (LineMessageID, error)
+	ReplyLocationContext(ctx context.Context, replyToken string, location Location) (LineMessageID, error)
+
+	// SendLocation [PAID] send location message to user
+	SendLocation(targetID string, location Location) (LineMessageID, error)
+	SendLocationContext(ctx context.Context, targetID string, location Location) (LineMessageID, error)
+
+	// ReplySticker [FREE] reply sticker message to user. Only NotifyMessageOption.QuoteToken is used.
+	ReplySticker(replyToken string, sticker Sticker, opt ...NotifyMessageOption) (LineMessageID, error)
+	ReplyStickerContext(ctx context.Context, replyToken string, sticker Sticker, opt ...NotifyMessageOption) (LineMessageID, error)
+
+	// SendSticker [PAID] send sticker message to user. Only NotifyMessageOption.QuoteToken is used.
+	SendSticker(targetID string, sticker Sticker, opt ...NotifyMessageOption) (LineMessageID, error)
+	SendStickerContext(ctx context.Context, targetID string, sticker Sticker, opt ...NotifyMessageOption) (LineMessageID, error)
+
+	// DownloadContent [FREE] streams the content of an image, video, audio or file message sent by user into w,
+	// and returns the number of bytes written.
+	//
+	// The content of video and audio messages may not be ready right after the event, see WaitContentReady.
+	DownloadContent(messageID string, w io.Writer) (int64, error)
+	DownloadContentContext(ctx context.Context, messageID string, w io.Writer) (int64, error)
+
+	// WaitContentReady [FREE] waits until LINE finishes preparing the content of a video or audio message.
+	// It returns an error wrapping [ErrContentTranscodingFailed] if LINE fails to prepare it.
+	WaitContentReady(ctx context.Context, messageID string) error
 }
 
 type lineNotifier struct {
 	bot       *messaging_api.MessagingApiAPI
+	blob      *messaging_api.MessagingApiBlobAPI
 	botUserID string
+	logger    *slog.Logger
 }
 
 // NewNotifier creates a new notifier which is used to send message to a user/group/room.
@@ -47,7 +131,17 @@ type lineNotifier struct {
 //
 //	// Send message to user/group/room. It costs money.
 //	notifier.SendMessage("targetID", "Hello, world!")
-func NewNotifier(channelAccessToken string) (Notifier, error) {
+func NewNotifier(channelAccessToken string, opt ...NotifierOption) (Notifier, error) {
+	option := NotifierOption{}
+	if len(opt) != 0 {
+		option = opt[0]
+	}
+
+	logger := option.Logger
+	if logger == nil {
+		logger = internal.NewLogger(os.Stderr)
+	}
+
 	bot, err := messaging_api.NewMessagingApiAPI(
 		channelAccessToken,
 	)
@@ -55,6 +149,13 @@ func NewNotifier(channelAccessToken string) (Notifier, error) {
 		return nil, fmt.Errorf("connect to bot, err: %+v", err)
 	}
 
+	blob, err := messaging_api.NewMessagingApiBlobAPI(
+		channelAccessToken,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("connect to blob, err: %+v", err)
+	}
+
 	info, err := bot.GetBotInfo()
 	if err != nil {
 		return nil, fmt.Errorf("get bot info, err: %+v", err)
@@ -63,88 +164,269 @@ func NewNotifier(channelAccessToken string) (Notifier, error) {
 	return &lineNotifier{
 		botUserID: info.UserId,
 		bot:       bot,
+		blob:      blob,
+		logger:    logger,
 	}, nil
 }
 
 func (r *lineNotifier) ReplyMessage(replyToken, text string, opt ...NotifyMessageOption) (LineMessageID, error) {
+	return r.ReplyMessageContext(context.Background(), replyToken, text, opt...)
+}
 
-	option := NotifyMessageOption{}
-	if len(opt) != 0 {
-		option = opt[0]
+func (r *lineNotifier) ReplyMessageContext(ctx context.Context, replyToken, text string, opt ...NotifyMessageOption) (LineMessageID, error) {
+	return firstID(r.reply(ctx, replyToken, newTextMessage(text, opt...)))
+}
+
+func (r *lineNotifier) SendMessage(targetID, text string, opt ...NotifyMessageOption) (LineMessageID, error) {
+	return r.SendMessageContext(context.Background(), targetID, text, opt...)
+}
+
+func (r *lineNotifier) SendMessageContext(ctx context.Context, targetID, text string, opt ...NotifyMessageOption) (LineMessageID, error) {
+	return firstID(r.push(ctx, targetID, newTextMessage(text, opt...)))
+}
+
+func (r *lineNotifier) ReplyMessages(replyToken string, messages ...Message) ([]SentMessage, error) {
+	return r.ReplyMessagesContext(context.Background(), replyToken, messages...)
+}
+
+func (r *lineNotifier) ReplyMessagesContext(ctx context.Context, replyToken string, messages ...Message) ([]SentMessage, error) {
+	msgs, err := messagingAPIMessages(messages)
+	if err != nil {
+		return nil, err
 	}
 
-	mentionMap := make(map[string]messaging_api.SubstitutionObjectInterface, len(option.MentionUserID))
-	for key, userID := range option.MentionUserID {
-		mentionMap[key] = messaging_api.MentionSubstitutionObject{
-			SubstitutionObject: messaging_api.SubstitutionObject{"mention"},
-			Mentionee: messaging_api.UserMentionTarget{
-				MentionTarget: messaging_api.MentionTarget{"user"},
-				UserId:        userID,
-			},
-		}
+	return r.reply(ctx, replyToken, msgs...)
+}
+
+func (r *lineNotifier) SendMessages(targetID string, messages ...Message) ([]SentMessage, error) {
+	return r.SendMessagesContext(context.Background(), targetID, messages...)
+}
+
+func (r *lineNotifier) SendMessagesContext(ctx context.Context, targetID string, messages ...Message) ([]SentMessage, error) {
+	msgs, err := messagingAPIMessages(messages)
+	if err != nil {
+		return nil, err
 	}
 
-	res, err := r.bot.ReplyMessage(
+	return r.push(ctx, targetID, msgs...)
+}
+
+func (r *lineNotifier) ReplyLocation(replyToken string, location Location) (LineMessageID, error) {
+	return r.ReplyLocationContext(context.Background(), replyToken, location)
+}
+
+func (r *lineNotifier) ReplyLocationContext(ctx context.Context, replyToken string, location Location) (LineMessageID, error) {
+	return firstID(r.reply(ctx, replyToken, newLocationMessage(location)))
+}
+
+func (r *lineNotifier) SendLocation(targetID string, location Location) (LineMessageID, error) {
+	return r.SendLocationContext(context.Background(), targetID, location)
+}
+
+func (r *lineNotifier) SendLocationContext(ctx context.Context, targetID string, location Location) (LineMessageID, error) {
+	return firstID(r.push(ctx, targetID, newLocationMessage(location)))
+}
+
+func (r *lineNotifier) ReplySticker(replyToken string, sticker Sticker, opt ...NotifyMessageOption) (LineMessageID, error) {
+	return r.ReplyStickerContext(context.Background(), replyToken, sticker, opt...)
+}
+
+func (r *lineNotifier) ReplyStickerContext(ctx context.Context, replyToken string, sticker Sticker, opt ...NotifyMessageOption) (LineMessageID, error) {
+	return firstID(r.reply(ctx, replyToken, newStickerMessage(sticker, opt...)))
+}
+
+func (r *lineNotifier) SendSticker(targetID string, sticker Sticker, opt ...NotifyMessageOption) (LineMessageID, error) {
+	return r.SendStickerContext(context.Background(), targetID, sticker, opt...)
+}
+
+func (r *lineNotifier) SendStickerContext(ctx context.Context, targetID string, sticker Sticker, opt ...NotifyMessageOption) (LineMessageID, error) {
+	return firstID(r.push(ctx, targetID, newStickerMessage(sticker, opt...)))
+}
+
+// api returns a copy of the messaging API client which sends requests with ctx.
+// The client is copied because WithContext modifies the client, which is shared by concurrent calls.
+func (r *lineNotifier) api(ctx context.Context) *messaging_api.MessagingApiAPI {
+	api := *r.bot
+	return api.WithContext(ctx)
+}
+
+// blobAPI returns a copy of the messaging API blob client which sends requests with ctx.
+func (r *lineNotifier) blobAPI(ctx context.Context) *messaging_api.MessagingApiBlobAPI {
+	blob := *r.blob
+	return blob.WithContext(ctx)
+}
+
+func (r *lineNotifier) reply(ctx context.Context, replyToken string, messages ...messaging_api.MessageInterface) ([]SentMessage, error) {
+	res, err := r.api(ctx).ReplyMessage(
 		&messaging_api.ReplyMessageRequest{
 			ReplyToken: replyToken,
-			Messages: []messaging_api.MessageInterface{
-				internal.TextMessageV2Fix{
-					Message:      messaging_api.Message{Type: "textV2"},
-					Text:         text,
-					Substitution: mentionMap,
-					QuoteToken:   option.QuoteToken,
-				},
-			},
+			Messages:   messages,
 		},
 	)
 	if err != nil {
-		return "", errors.Errorf("reply message, err: %+v", err)
+		r.logger.ErrorContext(ctx, "reply message", "error", err)
+		return nil, errors.Wrap(err, "reply message")
 	}
 
 	if len(res.SentMessages) == 0 {
-		return "", errors.New("no sent message")
+		r.logger.ErrorContext(ctx, "reply message", "error", "no sent message")
+		return nil, errors.New("no sent message")
 	}
 
-	return LineMessageID(res.SentMessages[0].Id), nil
+	sent := getSentMessages(res.SentMessages)
+	r.logger.DebugContext(ctx, "reply message", "message_id", sent[0].ID, "count", len(sent))
+
+	return sent, nil
 }
 
-func (r *lineNotifier) SendMessage(targetID, text string, opt ...NotifyMessageOption) (LineMessageID, error) {
+func (r *lineNotifier) push(ctx context.Context, targetID string, messages ...messaging_api.MessageInterface) ([]SentMessage, error) {
+	req := &messaging_api.PushMessageRequest{
+		To:       targetID,
+		Messages: messages,
+	}
+
+	res, err := r.api(ctx).PushMessage(req, "")
+	if err != nil {
+		r.logger.ErrorContext(ctx, "send message", "target_id", targetID, "error", err)
+		return nil, errors.Wrap(err, "send message")
+	}
+
+	if len(res.SentMessages) == 0 {
+		r.logger.ErrorContext(ctx, "send message", "target_id", targetID, "error", "no sent message")
+		return nil, errors.New("no sent message")
+	}
+
+	sent := getSentMessages(res.SentMessages)
+	r.logger.DebugContext(ctx, "send message", "target_id", targetID, "message_id", sent[0].ID, "count", len(sent))
+
+	return sent, nil
+}
+
+func getSentMessages(messages []messaging_api.SentMessage) []SentMessage {
+	sent := make([]SentMessage, len(messages))
+	for i, m := range messages {
+		sent[i] = SentMessage{ID: LineMessageID(m.Id), QuoteToken: m.QuoteToken}
+	}
+	return sent
+}
+
+// firstID returns the ID of the first sent message, for the methods sending a single message.
+func firstID(sent []SentMessage, err error) (LineMessageID, error) {
+	if err != nil {
+		return "", err
+	}
+	return sent[0].ID, nil
+}
+
+func newTextMessage(text string, opt ...NotifyMessageOption) messaging_api.MessageInterface {
 	option := NotifyMessageOption{}
 	if len(opt) != 0 {
 		option = opt[0]
 	}
 
-	mentionMap := make(map[string]messaging_api.SubstitutionObjectInterface, len(option.MentionUserID))
+	substitution := make(map[string]messaging_api.SubstitutionObjectInterface, len(option.MentionUserID)+len(option.MentionAll)+len(option.Emoji))
 	for key, userID := range option.MentionUserID {
-		mentionMap[key] = messaging_api.MentionSubstitutionObject{
-			SubstitutionObject: messaging_api.SubstitutionObject{"mention"},
+		substitution[key] = messaging_api.MentionSubstitutionObject{
+			SubstitutionObject: messaging_api.SubstitutionObject{Type: "mention"},
 			Mentionee: messaging_api.UserMentionTarget{
-				MentionTarget: messaging_api.MentionTarget{"user"},
+				MentionTarget: messaging_api.MentionTarget{Type: "user"},
 				UserId:        userID,
 			},
 		}
 	}
 
-	req := &messaging_api.PushMessageRequest{
-		To: targetID,
-		Messages: []messaging_api.MessageInterface{
-			internal.TextMessageV2Fix{
-				Message:      messaging_api.Message{Type: "textV2"},
-				Text:         text,
-				Substitution: mentionMap,
-				QuoteToken:   option.QuoteToken,
+	for _, key := range option.MentionAll {
+		substitution[key] = messaging_api.MentionSubstitutionObject{
+			SubstitutionObject: messaging_api.SubstitutionObject{Type: "mention"},
+			Mentionee: messaging_api.AllMentionTarget{
+				MentionTarget: messaging_api.MentionTarget{Type: "all"},
 			},
-		},
+		}
+	}
+
+	for key, emoji := range option.Emoji {
+		substitution[key] = messaging_api.EmojiSubstitutionObject{
+			SubstitutionObject: messaging_api.SubstitutionObject{Type: "emoji"},
+			ProductId:          emoji.ProductID,
+			EmojiId:            emoji.EmojiID,
+		}
+	}
+
+	return internal.TextMessageV2Fix{
+		Message:      messaging_api.Message{Type: "textV2"},
+		Text:         text,
+		Substitution: substitution,
+		QuoteToken:   option.QuoteToken,
+	}
+}
+
+func newStickerMessage(sticker Sticker, opt ...NotifyMessageOption) messaging_api.MessageInterface {
+	option := NotifyMessageOption{}
+	if len(opt) != 0 {
+		option = opt[0]
+	}
+
+	return messaging_api.StickerMessage{
+		Message:    messaging_api.Message{Type: "sticker"},
+		PackageId:  sticker.PackageID,
+		StickerId:  sticker.StickerID,
+		QuoteToken: option.QuoteToken,
+	}
+}
+
+func newLocationMessage(location Location) messaging_api.MessageInterface {
+	return messaging_api.LocationMessage{
+		Message:   messaging_api.Message{Type: "location"},
+		Title:     location.Title,
+		Address:   location.Address,
+		Latitude:  location.Latitude,
+		Longitude: location.Longitude,
 	}
+}
+
+func (r *lineNotifier) DownloadContent(messageID string, w io.Writer) (int64, error) {
+	return r.DownloadContentContext(context.Background(), messageID, w)
+}
 
-	res, err := r.bot.PushMessage(req, "")
+func (r *lineNotifier) DownloadContentContext(ctx context.Context, messageID string, w io.Writer) (int64, error) {
+	res, err := r.blobAPI(ctx).GetMessageContent(messageID)
 	if err != nil {
-		return "", errors.Errorf("send message, err: %+v", err)
+		r.logger.ErrorContext(ctx, "download content", "message_id", messageID, "error", err)
+		return 0, errors.Wrap(err, "get message content")
 	}
+	defer res.Body.Close()
 
-	if len(res.SentMessages) == 0 {
-		return "", errors.New("no sent message")
+	n, err := io.Copy(w, res.Body)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "download content", "message_id", messageID, "error", err)
+		return n, errors.Wrap(err, "copy message content")
 	}
 
-	return LineMessageID(res.SentMessages[0].Id), nil
+	r.logger.DebugContext(ctx, "download content", "message_id", messageID, "bytes", n)
+	return n, nil
+}
+
+func (r *lineNotifier) WaitContentReady(ctx context.Context, messageID string) error {
+	interval := contentPollMinInterval
+	for {
+		res, err := r.blobAPI(ctx).GetMessageContentTranscodingByMessageId(messageID)
+		if err != nil {
+			return errors.Wrap(err, "get message content transcoding")
+		}
+
+		switch res.Status {
+		case messaging_api.GetMessageContentTranscodingResponseSTATUS_SUCCEEDED:
+			return nil
+		case messaging_api.GetMessageContentTranscodingResponseSTATUS_FAILED:
+			return errors.Wrapf(ErrContentTranscodingFailed, "message %s", messageID)
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "wait content ready")
+		case <-time.After(interval):
+		}
+
+		interval = min(interval*2, contentPollMaxInterval)
+	}
 }
@@ -0,0 +1,156 @@
+package line
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yanun0323/line/internal"
+)
+
+func TestNewLocationMessage(t *testing.T) {
+	b, err := json.Marshal(newLocationMessage(Location{
+		Title:     "Store",
+		Address:   "Taipei",
+		Latitude:  25.03,
+		Longitude: 121.56,
+	}))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type":"location","title":"Store","address":"Taipei","latitude":25.03,"longitude":121.56}`, string(b))
+}
+
+func TestNewStickerMessage(t *testing.T) {
+	b, err := json.Marshal(newStickerMessage(Sticker{PackageID: "446", StickerID: "1988"}, NotifyMessageOption{QuoteToken: "q"}))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type":"sticker","packageId":"446","stickerId":"1988","quoteToken":"q"}`, string(b))
+}
+
+func TestNewTextMessage(t *testing.T) {
+	b, err := json.Marshal(newTextMessage("{user} {smile}", NotifyMessageOption{
+		MentionUserID: map[string]string{"user": "U1"},
+		Emoji:         map[string]Emoji{"smile": {ProductID: "5ac1bfd5040ab15980c9b435", EmojiID: "001"}},
+	}))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type":"textV2","text":"{user} {smile}","substitution":{
+		"user":{"type":"mention","mentionee":{"type":"user","userId":"U1"}},
+		"smile":{"type":"emoji","productId":"5ac1bfd5040ab15980c9b435","emojiId":"001"}
+	}}`, string(b))
+}
+
+func TestNotifierContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the body is drained, so that the server notices the canceled request.
+		b, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(b), "slow") {
+			<-r.Context().Done()
+			return
+		}
+		_, _ = w.Write([]byte(`{"sentMessages":[{"id":"M1","quoteToken":"q"}]}`))
+	}))
+	defer server.Close()
+
+	api, err := messaging_api.NewMessagingApiAPI("token", messaging_api.WithEndpoint(server.URL))
+	require.NoError(t, err)
+	n := &lineNotifier{bot: api, logger: internal.NewLogger(io.Discard)}
+
+	id, err := n.ReplyMessage("r", "hello")
+	require.NoError(t, err)
+	require.Equal(t, LineMessageID("M1"), id)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = n.SendMessageContext(ctx, "U1", "slow")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), 5*time.Second)
+
+	// the deadline is set on a copy of the client, so it doesn't leak into the shared one used by the next calls.
+	require.True(t, reflect.ValueOf(api).Elem().FieldByName("ctx").IsNil())
+	_, err = n.SendMessage("U1", "hello")
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			_, err := n.SendMessageContext(ctx, "U1", "hello")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = n.ReplyMessagesContext(canceled, "r", NewTextMessage("hello"))
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestNotifierContent(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		statuses = []string{"processing", "processing", "succeeded"}
+		polls    []time.Time
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/bot/message/M1/content":
+			_, _ = w.Write([]byte("content"))
+		case "/v2/bot/message/M1/content/transcoding":
+			mu.Lock()
+			defer mu.Unlock()
+			polls = append(polls, time.Now())
+			status := statuses[min(len(polls), len(statuses))-1]
+			_, _ = w.Write([]byte(`{"status":"` + status + `"}`))
+		case "/v2/bot/message/M2/content/transcoding":
+			_, _ = w.Write([]byte(`{"status":"failed"}`))
+		case "/v2/bot/message/M3/content/transcoding":
+			_, _ = w.Write([]byte(`{"status":"processing"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	blob, err := messaging_api.NewMessagingApiBlobAPI("token", messaging_api.WithBlobEndpoint(server.URL))
+	require.NoError(t, err)
+	n := &lineNotifier{blob: blob, logger: internal.NewLogger(io.Discard)}
+
+	buf := &bytes.Buffer{}
+	size, err := n.DownloadContent("M1", buf)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), size)
+	require.Equal(t, "content", buf.String())
+
+	_, err = n.DownloadContent("M404", buf)
+	require.Error(t, err)
+
+	require.NoError(t, n.WaitContentReady(context.Background(), "M1"))
+	require.Len(t, polls, 3)
+	require.GreaterOrEqual(t, polls[1].Sub(polls[0]), contentPollMinInterval)
+	require.GreaterOrEqual(t, polls[2].Sub(polls[1]), 2*contentPollMinInterval, "the interval should back off")
+
+	require.ErrorIs(t, n.WaitContentReady(context.Background(), "M2"), ErrContentTranscodingFailed)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	require.ErrorIs(t, n.WaitContentReady(ctx, "M3"), context.DeadlineExceeded)
+	require.Less(t, time.Since(start), contentPollMinInterval, "it should give up once the context is done")
+}
@@ -0,0 +1,221 @@
+package line
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrInvalidPostback is returned when the postback data can't be parsed or bound.
+	ErrInvalidPostback = errors.New("line: invalid postback")
+
+	// ErrUnknownPostbackAction is returned when no route matches the action of the postback and there is no fallback.
+	ErrUnknownPostbackAction = errors.New("line: unknown postback action")
+)
+
+const defaultPostbackActionKey = "action"
+
+// PostbackParams is the typed params of the postback from a datetime picker or a rich menu switch action.
+//
+// The date and time have no time zone in the postback, so they are parsed in UTC.
+type PostbackParams struct {
+	// Date is the date selected by a datetime picker in date mode.
+	Date *time.Time
+	// Time is the time selected by a datetime picker in time mode, on the zero date.
+	Time *time.Time
+	// Datetime is the date and time selected by a datetime picker in datetime mode.
+	Datetime *time.Time
+	// NewRichMenuAliasID is the rich menu alias ID switched to by a rich menu switch action.
+	NewRichMenuAliasID string
+	// RichMenuSwitchStatus is the result of a rich menu switch action,
+	// which is SUCCESS, RICHMENU_ALIAS_ID_NOTFOUND, RICHMENU_NOTFOUND or FAILED.
+	RichMenuSwitchStatus string
+}
+
+// ParsePostbackParams parses the params of [EventPostback].
+func ParsePostbackParams(params map[string]string) (PostbackParams, error) {
+	result := PostbackParams{
+		NewRichMenuAliasID:   params["newRichMenuAliasId"],
+		RichMenuSwitchStatus: params["status"],
+	}
+
+	for key, layout := range map[string]string{
+		"date":     time.DateOnly,
+		"time":     "15:04",
+		"datetime": "2006-01-02T15:04",
+	} {
+		s, ok := params[key]
+		if !ok {
+			continue
+		}
+
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			return PostbackParams{}, errors.Wrapf(ErrInvalidPostback, "parse %s param %q, err: %+v", key, s, err)
+		}
+
+		switch key {
+		case "date":
+			result.Date = &t
+		case "time":
+			result.Time = &t
+		case "datetime":
+			result.Datetime = &t
+		}
+	}
+
+	return result, nil
+}
+
+// Postback is the parsed postback which is passed to the route of a [PostbackRouter].
+type Postback[T any] struct {
+	// Action is the action of the postback.
+	Action string
+	// Payload is the postback data bound into T.
+	Payload T
+	// Params is the typed params of the postback.
+	Params PostbackParams
+}
+
+// PostbackRouter routes postback events by the action in the postback data,
+// which is either a query string like "action=buy&itemId=1" or a JSON object like {"action":"buy","itemId":1}.
+//
+// # Example:
+//
+//	type buyPayload struct {
+//		ItemID int `postback:"itemId"`
+//	}
+//
+//	router := line.NewPostbackRouter("action")
+//	line.HandlePostback(router, "buy", func(ctx context.Context, event line.EventPostback, postback line.Postback[buyPayload]) error {
+//		return buy(ctx, postback.Payload.ItemID)
+//	})
+//
+//	bot.SetPostbackEventHandler(router.HandleEvent)
+type PostbackRouter struct {
+	actionKey string
+	signer    *PostbackSigner
+	routes    map[string]func(ctx context.Context, event EventPostback, data postbackData) error
+	fallback  func(ctx context.Context, event EventPostback) error
+}
+
+// postbackData is the parsed postback data.
+type postbackData struct {
+	values url.Values
+	json   []byte
+}
+
+// NewPostbackRouter creates a router which routes postback events by the value of actionKey in the postback data.
+// Empty actionKey means "action".
+func NewPostbackRouter(actionKey string) *PostbackRouter {
+	if len(actionKey) == 0 {
+		actionKey = defaultPostbackActionKey
+	}
+
+	return &PostbackRouter{
+		actionKey: actionKey,
+		routes:    map[string]func(context.Context, EventPostback, postbackData) error{},
+	}
+}
+
+// HandlePostback sets the handler for the postback events with the action, which receives the postback data bound into T.
+//
+// For query string data, the fields of T are matched by the `postback` tag, then the `json` tag, then the field name case-insensitively.
+// For JSON data, T is decoded by [json.Unmarshal].
+func HandlePostback[T any](r *PostbackRouter, action string, fn func(ctx context.Context, event EventPostback, postback Postback[T]) error) {
+	r.routes[action] = func(ctx context.Context, event EventPostback, data postbackData) error {
+		postback := Postback[T]{Action: action}
+
+		if data.json != nil {
+			if err := json.Unmarshal(data.json, &postback.Payload); err != nil {
+				return errors.Wrapf(ErrInvalidPostback, "decode %s payload, err: %+v", action, err)
+			}
+		} else if err := bindValues(data.values, &postback.Payload, "postback"); err != nil {
+			return errors.Wrapf(ErrInvalidPostback, "bind %s payload, err: %+v", action, err)
+		}
+
+		params, err := ParsePostbackParams(event.Data.Params)
+		if err != nil {
+			return err
+		}
+		postback.Params = params
+
+		return fn(ctx, event, postback)
+	}
+}
+
+// Handle sets the handler for the postback events with the action, which doesn't need the postback data bound.
+func (r *PostbackRouter) Handle(action string, fn func(ctx context.Context, event EventPostback) error) {
+	r.routes[action] = func(ctx context.Context, event EventPostback, _ postbackData) error {
+		return fn(ctx, event)
+	}
+}
+
+// Fallback sets the handler for the postback events whose action has no route.
+func (r *PostbackRouter) Fallback(fn func(ctx context.Context, event EventPostback) error) {
+	r.fallback = fn
+}
+
+// UseSigner makes the router verify every postback data with the signer before routing it.
+// The postback events which fail the verification are rejected with the error from [PostbackSigner.Verify].
+func (r *PostbackRouter) UseSigner(signer *PostbackSigner) {
+	r.signer = signer
+}
+
+// HandleEvent routes the postback event. It can be set as the postback event handler of [Bot].
+func (r *PostbackRouter) HandleEvent(ctx context.Context, event EventPostback) error {
+	raw := event.Data.Data
+	if r.signer != nil {
+		verified, err := r.signer.Verify(raw)
+		if err != nil {
+			return err
+		}
+		raw = verified
+	}
+
+	data, action, err := r.parse(raw)
+	if err != nil {
+		return err
+	}
+
+	if route, ok := r.routes[action]; ok {
+		return route(ctx, event, data)
+	}
+
+	if r.fallback != nil {
+		return r.fallback(ctx, event)
+	}
+
+	return errors.Wrapf(ErrUnknownPostbackAction, "action %q", action)
+}
+
+// parse parses the postback data as a JSON object or a query string, and returns its action.
+func (r *PostbackRouter) parse(raw string) (postbackData, string, error) {
+	if strings.HasPrefix(strings.TrimSpace(raw), "{") {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(raw), &fields); err != nil {
+			return postbackData{}, "", errors.Wrapf(ErrInvalidPostback, "decode json data, err: %+v", err)
+		}
+
+		var action string
+		if v, ok := fields[r.actionKey]; ok {
+			if err := json.Unmarshal(v, &action); err != nil {
+				return postbackData{}, "", errors.Wrapf(ErrInvalidPostback, "decode %s, err: %+v", r.actionKey, err)
+			}
+		}
+
+		return postbackData{json: []byte(raw)}, action, nil
+	}
+
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return postbackData{}, "", errors.Wrapf(ErrInvalidPostback, "parse query data, err: %+v", err)
+	}
+
+	return postbackData{values: values}, values.Get(r.actionKey), nil
+}
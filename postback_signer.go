@@ -0,0 +1,169 @@
+package line
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrPostbackSignature is returned when the postback data isn't signed by the bot, or it has been tampered with.
+	ErrPostbackSignature = errors.New("line: invalid postback signature")
+
+	// ErrPostbackExpired is returned when the signed postback data has expired.
+	ErrPostbackExpired = errors.New("line: postback expired")
+)
+
+const (
+	// postbackDataMaxLength is the max number of characters of the postback data accepted by LINE.
+	postbackDataMaxLength = 300
+
+	signedPostbackPrefix    = "s1."
+	encryptedPostbackPrefix = "e1."
+
+	postbackMACSize = 16
+)
+
+// PostbackSignerOption is the option for the postback signer.
+type PostbackSignerOption struct {
+	// Encrypt makes the signer encrypt the postback data, so that users can't read it either.
+	Encrypt bool
+}
+
+// PostbackSigner signs and verifies postback data with keys derived from the channel secret,
+// so that the data sent back by users can be trusted.
+//
+// # Example:
+//
+//	signer := line.NewPostbackSigner("CHANNEL_SECRET")
+//	data, err := signer.Sign("action=refund&orderId=123", time.Now().Add(time.Hour))
+//
+//	router := line.NewPostbackRouter("action")
+//	router.UseSigner(signer)
+type PostbackSigner struct {
+	encrypt bool
+	macKey  []byte
+	aead    cipher.AEAD
+}
+
+// NewPostbackSigner creates a postback signer with keys derived from the channel secret.
+func NewPostbackSigner(channelSecret string, opt ...PostbackSignerOption) *PostbackSigner {
+	option := PostbackSignerOption{}
+	if len(opt) != 0 {
+		option = opt[0]
+	}
+
+	block, _ := aes.NewCipher(deriveKey(channelSecret, "postback-encrypt"))
+	aead, _ := cipher.NewGCM(block)
+
+	return &PostbackSigner{
+		encrypt: option.Encrypt,
+		macKey:  deriveKey(channelSecret, "postback-sign"),
+		aead:    aead,
+	}
+}
+
+func deriveKey(secret, purpose string) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(purpose))
+	return h.Sum(nil)
+}
+
+// Sign signs the postback data, which expires at expiresAt. Zero expiresAt means it never expires.
+//
+// It returns an error if the signed data is longer than the 300 characters accepted by LINE.
+func (s *PostbackSigner) Sign(data string, expiresAt time.Time) (string, error) {
+	payload := make([]byte, 8, 8+len(data))
+	if !expiresAt.IsZero() {
+		binary.BigEndian.PutUint64(payload, uint64(expiresAt.Unix()))
+	}
+	payload = append(payload, data...)
+
+	var signed string
+	if s.encrypt {
+		nonce := make([]byte, s.aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return "", errors.Errorf("generate nonce, err: %+v", err)
+		}
+
+		sealed := s.aead.Seal(nonce, nonce, payload, nil)
+		signed = encryptedPostbackPrefix + base64.RawURLEncoding.EncodeToString(sealed)
+	} else {
+		signed = signedPostbackPrefix +
+			base64.RawURLEncoding.EncodeToString(payload) + "." +
+			base64.RawURLEncoding.EncodeToString(s.mac(payload))
+	}
+
+	if n := utf8.RuneCountInString(signed); n > postbackDataMaxLength {
+		return "", errors.Errorf("signed postback data has %d characters, which exceeds %d", n, postbackDataMaxLength)
+	}
+
+	return signed, nil
+}
+
+// Verify verifies the signed postback data and returns the original data.
+//
+// It returns an error wrapping [ErrPostbackSignature] if the data isn't signed by the signer or has been tampered with,
+// or [ErrPostbackExpired] if it has expired.
+func (s *PostbackSigner) Verify(signed string) (string, error) {
+	var payload []byte
+	switch {
+	case strings.HasPrefix(signed, signedPostbackPrefix):
+		encoded, encodedMAC, ok := strings.Cut(strings.TrimPrefix(signed, signedPostbackPrefix), ".")
+		if !ok {
+			return "", errors.Wrap(ErrPostbackSignature, "malformed data")
+		}
+
+		p, err := base64.RawURLEncoding.DecodeString(encoded)
+		if err != nil {
+			return "", errors.Wrap(ErrPostbackSignature, "malformed payload")
+		}
+
+		mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+		if err != nil || !hmac.Equal(mac, s.mac(p)) {
+			return "", errors.Wrap(ErrPostbackSignature, "signature mismatch")
+		}
+
+		payload = p
+	case strings.HasPrefix(signed, encryptedPostbackPrefix):
+		sealed, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(signed, encryptedPostbackPrefix))
+		if err != nil || len(sealed) < s.aead.NonceSize() {
+			return "", errors.Wrap(ErrPostbackSignature, "malformed data")
+		}
+
+		nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+		p, err := s.aead.Open(nil, nonce, ciphertext, nil)
+		if err != nil {
+			return "", errors.Wrap(ErrPostbackSignature, "decryption failed")
+		}
+
+		payload = p
+	default:
+		return "", errors.Wrap(ErrPostbackSignature, "unsigned data")
+	}
+
+	if len(payload) < 8 {
+		return "", errors.Wrap(ErrPostbackSignature, "malformed payload")
+	}
+
+	if exp := int64(binary.BigEndian.Uint64(payload[:8])); exp != 0 && time.Now().Unix() >= exp {
+		return "", errors.Wrapf(ErrPostbackExpired, "expired at %s", time.Unix(exp, 0).Format(time.RFC3339))
+	}
+
+	return string(payload[8:]), nil
+}
+
+func (s *PostbackSigner) mac(payload []byte) []byte {
+	h := hmac.New(sha256.New, s.macKey)
+	h.Write(payload)
+	return h.Sum(nil)[:postbackMACSize]
+}
@@ -0,0 +1,109 @@
+package line
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostbackRouter(t *testing.T) {
+	type buyPayload struct {
+		ItemID   int      `postback:"itemId" json:"itemId"`
+		Quantity int      `json:"quantity"`
+		Tags     []string `postback:"tag"`
+		Gift     bool
+	}
+
+	var got Postback[buyPayload]
+	router := NewPostbackRouter("")
+	HandlePostback(router, "buy", func(ctx context.Context, event EventPostback, postback Postback[buyPayload]) error {
+		got = postback
+		return nil
+	})
+
+	newEvent := func(data string, params map[string]string) EventPostback {
+		return EventPostback{Data: EventPostbackData{Data: data, Params: params}}
+	}
+
+	require.NoError(t, router.HandleEvent(context.Background(), newEvent("action=buy&itemId=42&quantity=2&tag=a&tag=b&gift=true", nil)))
+	require.Equal(t, buyPayload{ItemID: 42, Quantity: 2, Tags: []string{"a", "b"}, Gift: true}, got.Payload)
+
+	require.NoError(t, router.HandleEvent(context.Background(), newEvent(`{"action":"buy","itemId":7}`, map[string]string{"datetime": "2024-03-01T10:30"})))
+	require.Equal(t, 7, got.Payload.ItemID)
+	require.Equal(t, time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC), *got.Params.Datetime)
+	require.Nil(t, got.Params.Date)
+
+	require.ErrorIs(t, router.HandleEvent(context.Background(), newEvent("action=buy&itemId=x", nil)), ErrInvalidPostback)
+	require.ErrorIs(t, router.HandleEvent(context.Background(), newEvent("action=sell", nil)), ErrUnknownPostbackAction)
+
+	fallback := false
+	router.Fallback(func(ctx context.Context, event EventPostback) error {
+		fallback = true
+		return nil
+	})
+	require.NoError(t, router.HandleEvent(context.Background(), newEvent("action=sell", nil)))
+	require.True(t, fallback)
+}
+
+func TestParsePostbackParams(t *testing.T) {
+	params, err := ParsePostbackParams(map[string]string{"newRichMenuAliasId": "menu-b", "status": "SUCCESS", "time": "13:05"})
+	require.NoError(t, err)
+	require.Equal(t, "menu-b", params.NewRichMenuAliasID)
+	require.Equal(t, "SUCCESS", params.RichMenuSwitchStatus)
+	require.Equal(t, 13, params.Time.Hour())
+	require.Equal(t, 5, params.Time.Minute())
+}
+
+func TestPostbackSigner(t *testing.T) {
+	for _, encrypt := range []bool{false, true} {
+		signer := NewPostbackSigner(testChannelSecret, PostbackSignerOption{Encrypt: encrypt})
+
+		signed, err := signer.Sign("action=refund&orderId=123", time.Now().Add(time.Hour))
+		require.NoError(t, err)
+
+		data, err := signer.Verify(signed)
+		require.NoError(t, err)
+		require.Equal(t, "action=refund&orderId=123", data)
+
+		tampered := signed[:len(signed)-2] + "AA"
+		if tampered == signed {
+			tampered = signed[:len(signed)-2] + "BB"
+		}
+		_, err = signer.Verify(tampered)
+		require.ErrorIs(t, err, ErrPostbackSignature)
+
+		_, err = NewPostbackSigner("another-secret", PostbackSignerOption{Encrypt: encrypt}).Verify(signed)
+		require.ErrorIs(t, err, ErrPostbackSignature)
+
+		expired, err := signer.Sign("action=refund", time.Now().Add(-time.Second))
+		require.NoError(t, err)
+		_, err = signer.Verify(expired)
+		require.ErrorIs(t, err, ErrPostbackExpired)
+
+		_, err = signer.Sign(strings.Repeat("x", 300), time.Time{})
+		require.Error(t, err)
+	}
+}
+
+func TestPostbackRouterSigner(t *testing.T) {
+	signer := NewPostbackSigner(testChannelSecret)
+	router := NewPostbackRouter("action")
+	router.UseSigner(signer)
+
+	var orderID string
+	HandlePostback(router, "refund", func(ctx context.Context, event EventPostback, postback Postback[struct{ OrderID string }]) error {
+		orderID = postback.Payload.OrderID
+		return nil
+	})
+
+	signed, err := signer.Sign("action=refund&orderId=123", time.Time{})
+	require.NoError(t, err)
+	require.NoError(t, router.HandleEvent(context.Background(), EventPostback{Data: EventPostbackData{Data: signed}}))
+	require.Equal(t, "123", orderID)
+
+	err = router.HandleEvent(context.Background(), EventPostback{Data: EventPostbackData{Data: "action=refund&orderId=456"}})
+	require.ErrorIs(t, err, ErrPostbackSignature)
+}
@@ -0,0 +1,140 @@
+package line
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrTextTooLong is returned when the built text is longer than LINE accepts.
+	ErrTextTooLong = errors.New("line: text too long")
+
+	// ErrTooManySubstitutions is returned when the built text has more mentions and emojis than LINE accepts.
+	ErrTooManySubstitutions = errors.New("line: too many substitutions")
+)
+
+const (
+	// textMaxLength is the max number of characters of a text message accepted by LINE.
+	textMaxLength = 5000
+	// substitutionMaxCount is the max number of substitutions of a textV2 message accepted by LINE.
+	substitutionMaxCount = 100
+)
+
+// TextBuilder builds the text of a message with mentions and LINE emojis.
+//
+// The plain text is escaped, so the braces in user content aren't taken as substitution keys.
+//
+// # Example:
+//
+//	text, opt, err := line.NewTextBuilder().
+//		Mention(event.Source.UserID).
+//		Text(" {your} order is ready ").
+//		Emoji("5ac1bfd5040ab15980c9b435", "001").
+//		Build()
+//	if err != nil {
+//		return err
+//	}
+//
+//	opt.QuoteToken = event.Data.QuoteToken
+//	_, err = notifier.ReplyMessage(event.Data.ReplyToken, text, opt)
+type TextBuilder struct {
+	sb         strings.Builder
+	mentions   map[string]string /* user ID to key */
+	mentionAll string
+	emojis     map[Emoji]string /* emoji to key */
+	option     NotifyMessageOption
+}
+
+// NewTextBuilder creates a new text builder.
+func NewTextBuilder() *TextBuilder {
+	return &TextBuilder{
+		mentions: map[string]string{},
+		emojis:   map[Emoji]string{},
+		option: NotifyMessageOption{
+			MentionUserID: map[string]string{},
+			Emoji:         map[string]Emoji{},
+		},
+	}
+}
+
+// Text appends the plain text.
+func (b *TextBuilder) Text(text string) *TextBuilder {
+	b.sb.WriteString(strings.NewReplacer("{", "{{", "}", "}}").Replace(text))
+	return b
+}
+
+// Mention appends a mention of the user.
+func (b *TextBuilder) Mention(userID string) *TextBuilder {
+	key, ok := b.mentions[userID]
+	if !ok {
+		key = b.nextKey("user")
+		b.mentions[userID] = key
+		b.option.MentionUserID[key] = userID
+	}
+
+	b.sb.WriteString(NewMention(key))
+	return b
+}
+
+// MentionAll appends a mention of all the members of the group or room, which is shown as @All.
+func (b *TextBuilder) MentionAll() *TextBuilder {
+	if len(b.mentionAll) == 0 {
+		b.mentionAll = b.nextKey("all")
+		b.option.MentionAll = append(b.option.MentionAll, b.mentionAll)
+	}
+
+	b.sb.WriteString(NewMention(b.mentionAll))
+	return b
+}
+
+// Emoji appends a LINE emoji. See https://developers.line.biz/en/docs/messaging-api/emoji-list/ for the available emojis.
+func (b *TextBuilder) Emoji(productID, emojiID string) *TextBuilder {
+	emoji := Emoji{ProductID: productID, EmojiID: emojiID}
+	key, ok := b.emojis[emoji]
+	if !ok {
+		key = b.nextKey("emoji")
+		b.emojis[emoji] = key
+		b.option.Emoji[key] = emoji
+	}
+
+	b.sb.WriteString(NewMention(key))
+	return b
+}
+
+// nextKey returns a new substitution key, which is unique in the text.
+func (b *TextBuilder) nextKey(prefix string) string {
+	return prefix + strconv.Itoa(len(b.option.MentionUserID)+len(b.option.MentionAll)+len(b.option.Emoji))
+}
+
+// Build returns the text and the option carrying its substitutions, which can be passed to [Notifier.ReplyMessage] or [Notifier.SendMessage].
+//
+// It returns an error wrapping [ErrTooManySubstitutions] if there are more than 100 mentions and emojis,
+// or [ErrTextTooLong] if the text is longer than 5000 characters.
+func (b *TextBuilder) Build() (string, NotifyMessageOption, error) {
+	text := b.sb.String()
+
+	if n := len(b.option.MentionUserID) + len(b.option.MentionAll) + len(b.option.Emoji); n > substitutionMaxCount {
+		return "", NotifyMessageOption{}, errors.Wrapf(ErrTooManySubstitutions, "%d substitutions, which exceeds %d", n, substitutionMaxCount)
+	}
+
+	if n := len(utf16.Encode([]rune(text))); n > textMaxLength {
+		return "", NotifyMessageOption{}, errors.Wrapf(ErrTextTooLong, "%d characters, which exceeds %d", n, textMaxLength)
+	}
+
+	option := NotifyMessageOption{
+		MentionUserID: make(map[string]string, len(b.option.MentionUserID)),
+		MentionAll:    append([]string{}, b.option.MentionAll...),
+		Emoji:         make(map[string]Emoji, len(b.option.Emoji)),
+	}
+	for key, userID := range b.option.MentionUserID {
+		option.MentionUserID[key] = userID
+	}
+	for key, emoji := range b.option.Emoji {
+		option.Emoji[key] = emoji
+	}
+
+	return text, option, nil
+}
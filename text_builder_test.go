@@ -0,0 +1,39 @@
+package line
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTextBuilder(t *testing.T) {
+	text, opt, err := NewTextBuilder().
+		Mention("U1").
+		Text(" {not a key} ").
+		MentionAll().
+		Emoji("5ac1bfd5040ab15980c9b435", "001").
+		Mention("U1").
+		Build()
+	require.NoError(t, err)
+	require.Equal(t, "{user0} {{not a key}} {all1}{emoji2}{user0}", text)
+
+	b, err := json.Marshal(newTextMessage(text, opt))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type":"textV2","text":"{user0} {{not a key}} {all1}{emoji2}{user0}","substitution":{
+		"user0":{"type":"mention","mentionee":{"type":"user","userId":"U1"}},
+		"all1":{"type":"mention","mentionee":{"type":"all"}},
+		"emoji2":{"type":"emoji","productId":"5ac1bfd5040ab15980c9b435","emojiId":"001"}
+	}}`, string(b))
+
+	_, _, err = NewTextBuilder().Text(strings.Repeat("a", 5001)).Build()
+	require.ErrorIs(t, err, ErrTextTooLong)
+
+	tb := NewTextBuilder()
+	for i := range 101 {
+		tb.Mention("U" + strings.Repeat("0", i))
+	}
+	_, _, err = tb.Build()
+	require.ErrorIs(t, err, ErrTooManySubstitutions)
+}